@@ -8,20 +8,24 @@ import (
 	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	"github.com/cloudflare/origin-ca-issuer/cmd/controller/options"
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	"github.com/cloudflare/origin-ca-issuer/internal/roots"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/cloudflare/origin-ca-issuer/pkgs/controllers"
 	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
 	"github.com/go-logr/zerologr"
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
+	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -70,6 +74,26 @@ func main() {
 
 	mgr, err := manager.New(kubeCfg, manager.Options{
 		Scheme: scheme,
+
+		Metrics: metricsserver.Options{
+			BindAddress:   o.MetricsBindAddress,
+			SecureServing: o.MetricsSecure,
+			CertDir:       o.MetricsCertDir,
+		},
+		HealthProbeBindAddress: o.HealthProbeBindAddress,
+
+		// Only the leader runs the controllers, so that replicas do not sign
+		// the same CertificateRequests. Provisioners are loaded by the issuer
+		// controllers once a replica becomes the leader. The Lease is released
+		// once the controllers have stopped, so that a replacement replica can
+		// take over without waiting for it to expire.
+		LeaderElection:                o.LeaderElect,
+		LeaderElectionNamespace:       o.LeaderElectionNamespace,
+		LeaderElectionID:              o.LeaderElectionID,
+		LeaderElectionReleaseOnCancel: true,
+		LeaseDuration:                 &o.LeaderElectionLeaseDuration,
+		RenewDeadline:                 &o.LeaderElectionRenewDeadline,
+		RetryPeriod:                   &o.LeaderElectionRetryPeriod,
 	})
 	if err != nil {
 		log.Error(err, "could not create manager")
@@ -77,24 +101,114 @@ func main() {
 	}
 
 	collection := provisioners.CollectionWith(nil)
+	watchdog := &controllers.ReconcileWatchdog{
+		Clock:   clock.RealClock{},
+		Timeout: o.LivenessReconcileTimeout,
+	}
+
+	if err := mgr.AddHealthzCheck("reconcile", watchdog.Check); err != nil {
+		log.Error(err, "could not add liveness check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("informers", controllers.CacheSynced(mgr.GetCache())); err != nil {
+		log.Error(err, "could not add readiness check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("provisioners", controllers.ProvisionersLoaded(mgr.GetClient(), collection, mgr.Elected())); err != nil {
+		log.Error(err, "could not add readiness check")
+		os.Exit(1)
+	}
+
+	originRoots, err := roots.Load(o.OriginCARootsFile)
+	if err != nil {
+		log.Error(err, "could not load Origin CA roots")
+		os.Exit(1)
+	}
 
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
 	}
-	f := cfapi.FactoryFunc(func(serviceKey []byte) (cfapi.Interface, error) {
-		return cfapi.New(serviceKey, cfapi.WithClient(httpClient)), nil
+	apiOptions := []cfapi.Options{
+		cfapi.WithClient(httpClient),
+		cfapi.WithRetryPolicy(cfapi.DefaultRetryPolicy),
+	}
+	if o.CloudflareAPIEndpoint != "" {
+		endpoint, err := cfapi.WithEndpoint(o.CloudflareAPIEndpoint)
+		if err != nil {
+			log.Error(err, "could not parse Cloudflare API endpoint")
+			os.Exit(1)
+		}
+
+		apiOptions = append(apiOptions, endpoint)
+	}
+
+	f := cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+		options := append([]cfapi.Options{cfapi.WithAPIToken(creds.APIToken)}, apiOptions...)
+
+		return cfapi.New(creds.ServiceKey, options...), nil
 	})
 
+	ctx := signals.SetupSignalHandler()
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1.OriginClusterIssuer{}, controllers.SecretRefField, controllers.IndexSecretRef); err != nil {
+		log.Error(err, "could not index origin cluster issuers")
+		os.Exit(1)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1.OriginIssuer{}, controllers.SecretRefField, controllers.IndexSecretRef); err != nil {
+		log.Error(err, "could not index origin issuers")
+		os.Exit(1)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &certmanager.CertificateRequest{}, controllers.IssuerRefField, controllers.IndexIssuerRef); err != nil {
+		log.Error(err, "could not index certificate requests")
+		os.Exit(1)
+	}
+
+	clusterIssuerController := &controllers.OriginClusterIssuerController{
+		Client:     mgr.GetClient(),
+		Clock:      clock.RealClock{},
+		Factory:    f,
+		Log:        log.WithName("controllers").WithName("OriginClusterIssuer"),
+		Recorder:   mgr.GetEventRecorderFor("origin-ca-issuer"),
+		Collection: collection,
+
+		VerifyInterval:  o.IssuerVerifyInterval,
+		EnableFinalizer: o.EnableIssuerFinalizer,
+	}
+
+	// Build the provisioners of Ready issuers on demand, so that
+	// CertificateRequests reconciled before their issuer are not delayed.
+	collection.SetLoader(clusterIssuerController.LoadProvisioner)
+
 	err = builder.
 		ControllerManagedBy(mgr).
 		For(&v1.OriginClusterIssuer{}).
-		Complete(reconcile.AsReconciler(mgr.GetClient(), &controllers.OriginClusterIssuerController{
-			Client:     mgr.GetClient(),
-			Clock:      clock.RealClock{},
-			Factory:    f,
-			Log:        log.WithName("controllers").WithName("OriginClusterIssuer"),
-			Collection: collection,
-		}))
+		Watches(&core.Secret{}, handler.EnqueueRequestsFromMapFunc(controllers.IssuersForSecret(mgr.GetClient(), &v1.OriginClusterIssuerList{}))).
+		Watches(&v1.OriginClusterIssuer{}, controllers.EvictDeleted(collection)).
+		Complete(watchdog.Wrap(reconcile.AsReconciler(mgr.GetClient(), clusterIssuerController)))
+
+	if err != nil {
+		log.Error(err, "could not create origin cluster issuer controller")
+		os.Exit(1)
+	}
+
+	err = builder.
+		ControllerManagedBy(mgr).
+		For(&v1.OriginIssuer{}).
+		Watches(&core.Secret{}, handler.EnqueueRequestsFromMapFunc(controllers.IssuersForSecret(mgr.GetClient(), &v1.OriginIssuerList{}))).
+		Watches(&v1.OriginIssuer{}, controllers.EvictDeleted(collection)).
+		Complete(watchdog.Wrap(reconcile.AsReconciler(mgr.GetClient(), &controllers.OriginIssuerController{
+			OriginClusterIssuerController: controllers.OriginClusterIssuerController{
+				Client:     mgr.GetClient(),
+				Clock:      clock.RealClock{},
+				Factory:    f,
+				Log:        log.WithName("controllers").WithName("OriginIssuer"),
+				Recorder:   mgr.GetEventRecorderFor("origin-ca-issuer"),
+				Collection: collection,
+
+				VerifyInterval:  o.IssuerVerifyInterval,
+				EnableFinalizer: o.EnableIssuerFinalizer,
+			},
+		})))
 
 	if err != nil {
 		log.Error(err, "could not create origin issuer controller")
@@ -104,21 +218,25 @@ func main() {
 	err = builder.
 		ControllerManagedBy(mgr).
 		For(&certmanager.CertificateRequest{}).
-		Complete(reconcile.AsReconciler(mgr.GetClient(), &controllers.CertificateRequestController{
+		Watches(&v1.OriginClusterIssuer{}, controllers.RequeuePendingRequests(mgr.GetClient())).
+		Watches(&v1.OriginIssuer{}, controllers.RequeuePendingRequests(mgr.GetClient())).
+		Complete(watchdog.Wrap(reconcile.AsReconciler(mgr.GetClient(), &controllers.CertificateRequestController{
 			Client:     mgr.GetClient(),
 			Log:        log.WithName("controllers").WithName("CertificateRequest"),
+			Recorder:   mgr.GetEventRecorderFor("origin-ca-issuer"),
 			Collection: collection,
+			Roots:      originRoots,
 
 			Clock:                  clock.RealClock{},
 			CheckApprovedCondition: !o.DisableApprovedCheck,
-		}))
+		})))
 
 	if err != nil {
 		log.Error(err, "could not create certificaterequest controller")
 		os.Exit(1)
 	}
 
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		log.Error(err, "could not start manager")
 		os.Exit(1)
 	}
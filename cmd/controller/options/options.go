@@ -2,6 +2,8 @@ package options
 
 import (
 	"fmt"
+	"net/url"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -10,18 +12,59 @@ type ControllerOptions struct {
 	KubernetesAPIQPS   float32
 	KubernetesAPIBurst int
 
-	DisableApprovedCheck bool
+	DisableApprovedCheck  bool
+	EnableIssuerFinalizer bool
+
+	CloudflareAPIEndpoint string
+	OriginCARootsFile     string
+
+	IssuerVerifyInterval time.Duration
+
+	MetricsBindAddress       string
+	MetricsSecure            bool
+	MetricsCertDir           string
+	HealthProbeBindAddress   string
+	LivenessReconcileTimeout time.Duration
+
+	LeaderElect                 bool
+	LeaderElectionNamespace     string
+	LeaderElectionID            string
+	LeaderElectionLeaseDuration time.Duration
+	LeaderElectionRenewDeadline time.Duration
+	LeaderElectionRetryPeriod   time.Duration
 }
 
 const (
 	defaultKubernetesAPIQPS   float32 = 20
 	defaultKubernetesAPIBurst int     = 50
+
+	defaultIssuerVerifyInterval = time.Hour
+
+	defaultMetricsBindAddress       = ":8080"
+	defaultHealthProbeBindAddress   = ":8081"
+	defaultLivenessReconcileTimeout = 10 * time.Minute
+
+	defaultLeaderElectionID            = "origin-ca-issuer-leader-election"
+	defaultLeaderElectionLeaseDuration = 15 * time.Second
+	defaultLeaderElectionRenewDeadline = 10 * time.Second
+	defaultLeaderElectionRetryPeriod   = 2 * time.Second
 )
 
 func NewControllerOptions() *ControllerOptions {
 	return &ControllerOptions{
 		KubernetesAPIQPS:   defaultKubernetesAPIQPS,
 		KubernetesAPIBurst: defaultKubernetesAPIBurst,
+
+		IssuerVerifyInterval: defaultIssuerVerifyInterval,
+
+		MetricsBindAddress:       defaultMetricsBindAddress,
+		HealthProbeBindAddress:   defaultHealthProbeBindAddress,
+		LivenessReconcileTimeout: defaultLivenessReconcileTimeout,
+
+		LeaderElectionID:            defaultLeaderElectionID,
+		LeaderElectionLeaseDuration: defaultLeaderElectionLeaseDuration,
+		LeaderElectionRenewDeadline: defaultLeaderElectionRenewDeadline,
+		LeaderElectionRetryPeriod:   defaultLeaderElectionRetryPeriod,
 	}
 }
 
@@ -29,6 +72,21 @@ func (o *ControllerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.Float32Var(&o.KubernetesAPIQPS, "kube-api-qps", defaultKubernetesAPIQPS, "Maximium queries-per-second of requests to the Kubernetes apiserver.")
 	fs.IntVar(&o.KubernetesAPIBurst, "kube-api-burst", defaultKubernetesAPIBurst, "Maximium queries-per-second burst of request send to the Kubernetes apiserver.")
 	fs.BoolVar(&o.DisableApprovedCheck, "disable-approved-check", o.DisableApprovedCheck, "Disables waiting for CertificateRequests to have an approved condition before signing.")
+	fs.BoolVar(&o.EnableIssuerFinalizer, "enable-issuer-finalizer", o.EnableIssuerFinalizer, "Blocks the deletion of issuers while CertificateRequests referencing them are pending.")
+	fs.StringVar(&o.CloudflareAPIEndpoint, "cloudflare-api-endpoint", o.CloudflareAPIEndpoint, "Overrides the Cloudflare API endpoint, such as to use a fake-origin-ca server.")
+	fs.StringVar(&o.OriginCARootsFile, "origin-ca-roots-file", o.OriginCARootsFile, "Path to a PEM bundle of Origin CA roots, overriding the embedded roots set as the CA of issued certificates.")
+	fs.DurationVar(&o.IssuerVerifyInterval, "issuer-verify-interval", defaultIssuerVerifyInterval, "How often to re-verify issuer credentials with the Cloudflare API. Set to 0 to only verify credentials when an issuer or its secret changes.")
+	fs.StringVar(&o.MetricsBindAddress, "metrics-bind-address", defaultMetricsBindAddress, "Address to serve metrics on. Set to 0 to disable the metrics server.")
+	fs.BoolVar(&o.MetricsSecure, "metrics-secure", o.MetricsSecure, "Serves metrics over HTTPS instead of HTTP.")
+	fs.StringVar(&o.MetricsCertDir, "metrics-cert-dir", o.MetricsCertDir, "Directory containing the tls.crt and tls.key used to serve metrics over HTTPS. A self-signed certificate is generated if unset.")
+	fs.StringVar(&o.HealthProbeBindAddress, "health-probe-bind-address", defaultHealthProbeBindAddress, "Address to serve the /healthz and /readyz probes on. Set to 0 to disable the probes.")
+	fs.DurationVar(&o.LivenessReconcileTimeout, "liveness-reconcile-timeout", defaultLivenessReconcileTimeout, "How long a reconcile may run before the liveness probe fails.")
+	fs.BoolVar(&o.LeaderElect, "leader-elect", o.LeaderElect, "Elects a leader among the controller replicas, so that only one of them signs certificates.")
+	fs.StringVar(&o.LeaderElectionNamespace, "leader-election-namespace", o.LeaderElectionNamespace, "Namespace of the leader election Lease. Defaults to the namespace the controller runs in.")
+	fs.StringVar(&o.LeaderElectionID, "leader-election-id", defaultLeaderElectionID, "Name of the leader election Lease.")
+	fs.DurationVar(&o.LeaderElectionLeaseDuration, "leader-election-lease-duration", defaultLeaderElectionLeaseDuration, "How long non-leader replicas wait before trying to acquire a Lease that was not renewed.")
+	fs.DurationVar(&o.LeaderElectionRenewDeadline, "leader-election-renew-deadline", defaultLeaderElectionRenewDeadline, "How long the leader retries renewing its Lease before giving up leadership.")
+	fs.DurationVar(&o.LeaderElectionRetryPeriod, "leader-election-retry-period", defaultLeaderElectionRetryPeriod, "How long replicas wait between attempts to acquire or renew the Lease.")
 }
 
 func (o *ControllerOptions) Validate() error {
@@ -40,5 +98,41 @@ func (o *ControllerOptions) Validate() error {
 		return fmt.Errorf("invalid value for kube-api-qps: %v must be higher than 0", o.KubernetesAPIQPS)
 	}
 
+	if o.IssuerVerifyInterval < 0 {
+		return fmt.Errorf("invalid value for issuer-verify-interval: %v must not be negative", o.IssuerVerifyInterval)
+	}
+
+	if o.LivenessReconcileTimeout <= 0 {
+		return fmt.Errorf("invalid value for liveness-reconcile-timeout: %v must be higher than 0", o.LivenessReconcileTimeout)
+	}
+
+	if o.MetricsCertDir != "" && !o.MetricsSecure {
+		return fmt.Errorf("invalid value for metrics-cert-dir: requires metrics-secure")
+	}
+
+	if o.LeaderElect {
+		if o.LeaderElectionID == "" {
+			return fmt.Errorf("invalid value for leader-election-id: must not be empty")
+		}
+
+		if o.LeaderElectionRetryPeriod <= 0 {
+			return fmt.Errorf("invalid value for leader-election-retry-period: %v must be higher than 0", o.LeaderElectionRetryPeriod)
+		}
+
+		if o.LeaderElectionRenewDeadline <= o.LeaderElectionRetryPeriod {
+			return fmt.Errorf("invalid value for leader-election-renew-deadline: %v must be higher than leader-election-retry-period", o.LeaderElectionRenewDeadline)
+		}
+
+		if o.LeaderElectionLeaseDuration <= o.LeaderElectionRenewDeadline {
+			return fmt.Errorf("invalid value for leader-election-lease-duration: %v must be higher than leader-election-renew-deadline", o.LeaderElectionLeaseDuration)
+		}
+	}
+
+	if o.CloudflareAPIEndpoint != "" {
+		if u, err := url.Parse(o.CloudflareAPIEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid value for cloudflare-api-endpoint: %q must be an absolute URL", o.CloudflareAPIEndpoint)
+		}
+	}
+
 	return nil
 }
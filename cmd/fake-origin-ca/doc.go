@@ -0,0 +1,27 @@
+/*
+Fake-origin-ca serves an in-memory implementation of the Cloudflare Origin CA
+API, signing certificates with a locally generated root. Point the controller
+at it with --cloudflare-api-endpoint to run origin-ca-issuer without a
+Cloudflare account.
+
+Command Line
+
+Flags:
+
+    --listen-address string
+        Address to serve the API on. (default ":8080")
+    --tls-cert-file string
+        Serve over TLS with the certificate in this file.
+    --tls-key-file string
+        Serve over TLS with the private key in this file.
+    --root-ca-file string
+        Write the PEM encoded root certificate to this file.
+    --faults-file string
+        Inject the JSON array of faults in this file.
+    --credentials strings
+        Accept only these service keys or API tokens.
+    --zones strings
+        Register zones as id=name pairs, for listing certificates by zone.
+
+*/
+package main
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi/fake"
+	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	fs := pflag.CommandLine
+
+	listenAddress := fs.String("listen-address", ":8080", "Address to serve the API on.")
+	tlsCertFile := fs.String("tls-cert-file", "", "Serve over TLS with the certificate in this file.")
+	tlsKeyFile := fs.String("tls-key-file", "", "Serve over TLS with the private key in this file.")
+	rootCAFile := fs.String("root-ca-file", "", "Write the PEM encoded root certificate to this file.")
+	faultsFile := fs.String("faults-file", "", "Inject the JSON array of faults in this file.")
+	credentials := fs.StringSlice("credentials", nil, "Accept only these service keys or API tokens.")
+	zones := fs.StringSlice("zones", nil, "Register zones as id=name pairs, for listing certificates by zone.")
+
+	_ = fs.Parse(os.Args[1:])
+
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMs
+	log := zerolog.New(os.Stderr).With().Timestamp().Str("logger", "fake-origin-ca").Logger()
+
+	options := []fake.Option{fake.WithCredentials(*credentials...)}
+	for _, z := range *zones {
+		id, name, ok := strings.Cut(z, "=")
+		if !ok {
+			log.Fatal().Str("zone", z).Msg("zones must be id=name pairs")
+		}
+
+		options = append(options, fake.WithZone(id, name))
+	}
+
+	s, err := fake.New(options...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not create fake Origin CA")
+	}
+
+	if *rootCAFile != "" {
+		if err := os.WriteFile(*rootCAFile, s.RootPEM(), 0o644); err != nil {
+			log.Fatal().Err(err).Msg("could not write root certificate")
+		}
+	}
+
+	if *faultsFile != "" {
+		p, err := os.ReadFile(*faultsFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not read faults")
+		}
+
+		faults, err := fake.UnmarshalFaults(p)
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not decode faults")
+		}
+
+		s.Inject(faults...)
+	}
+
+	srv := &http.Server{
+		Addr:              *listenAddress,
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("request")
+			s.ServeHTTP(w, r)
+		}),
+	}
+
+	log.Info().Str("address", *listenAddress).Msg("serving fake Origin CA API")
+
+	if *tlsCertFile != "" || *tlsKeyFile != "" {
+		err = srv.ListenAndServeTLS(*tlsCertFile, *tlsKeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+
+	log.Fatal().Err(err).Msg("could not serve fake Origin CA API")
+}
@@ -4,20 +4,30 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/metrics"
 )
 
 type Interface interface {
 	Sign(context.Context, *SignRequest) (*SignResponse, error)
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	Get(context.Context, string) (*Certificate, error)
+	Revoke(context.Context, string) (*RevokeResponse, error)
 }
 
 type Client struct {
 	serviceKey []byte
+	token      []byte
 	client     *http.Client
 	endpoint   string
+	retry      RetryPolicy
 }
 
 func New(serviceKey []byte, options ...Options) *Client {
@@ -42,6 +52,16 @@ func WithClient(client *http.Client) Options {
 	}
 }
 
+// WithAPIToken authenticates requests with an API Token sent as a Bearer token
+// instead of the service key. An empty token leaves the client unchanged.
+func WithAPIToken(token []byte) Options {
+	return func(c *Client) {
+		if len(token) > 0 {
+			c.token = token
+		}
+	}
+}
+
 func WithEndpoint(endpoint string) (Options, error) {
 	u, err := url.Parse(endpoint)
 	if err != nil {
@@ -70,13 +90,51 @@ type SignResponse struct {
 	Type        string    `json:"request_type"`
 	Validity    int       `json:"requested_validity"`
 	CSR         string    `json:"csr"`
+
+	// RayID is the CF-Ray ID of the response the certificate was returned in.
+	RayID string `json:"-"`
+}
+
+// Certificate is an Origin CA certificate as returned by the Cloudflare API.
+type Certificate = SignResponse
+
+// ListRequest filters and paginates the certificates returned by List.
+type ListRequest struct {
+	// ZoneID limits the results to certificates covering hostnames in the zone.
+	ZoneID string
+	// Page is the 1-indexed page to return. Zero requests the first page.
+	Page int
+	// PerPage is the number of certificates per page. Zero uses the API default.
+	PerPage int
+}
+
+type ListResponse struct {
+	Certificates []Certificate
+	ResultInfo   ResultInfo
+}
+
+type RevokeResponse struct {
+	Id        string    `json:"id"`
+	RevokedAt time.Time `json:"revoked_at"`
 }
 
 type APIResponse struct {
-	Success  bool            `json:"success"`
-	Errors   []APIError      `json:"errors"`
-	Messages []string        `json:"messages"`
-	Result   json.RawMessage `json:"result"`
+	Success    bool            `json:"success"`
+	Errors     []APIError      `json:"errors"`
+	Messages   []string        `json:"messages"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo *ResultInfo     `json:"result_info,omitempty"`
+
+	// RayID is the CF-Ray ID of the response.
+	RayID string `json:"-"`
+}
+
+type ResultInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Count      int `json:"count"`
+	TotalCount int `json:"total_count"`
+	TotalPages int `json:"total_pages"`
 }
 
 type APIError struct {
@@ -95,39 +153,190 @@ func (c *Client) Sign(ctx context.Context, req *SignRequest) (*SignResponse, err
 		return nil, err
 	}
 
-	r, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewBuffer(p))
+	api, err := c.do(ctx, "sign", "POST", c.endpoint, p)
 	if err != nil {
 		return nil, err
 	}
 
-	r.Header.Add("User-Agent", "github.com/cloudflare/origin-ca-issuer")
-	r.Header.Add("X-Auth-User-Service-Key", string(c.serviceKey))
+	signResp := SignResponse{}
+	if err := json.Unmarshal(api.Result, &signResp); err != nil {
+		return nil, err
+	}
+
+	signResp.RayID = api.RayID
+
+	return &signResp, nil
+}
+
+// List returns a single page of the Origin CA certificates visible to the
+// credentials, optionally filtered to a zone.
+func (c *Client) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	if req.ZoneID != "" {
+		q.Set("zone_id", req.ZoneID)
+	}
+	if req.Page > 0 {
+		q.Set("page", strconv.Itoa(req.Page))
+	}
+	if req.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(req.PerPage))
+	}
+	u.RawQuery = q.Encode()
+
+	api, err := c.do(ctx, "list", "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	listResp := ListResponse{}
+	if err := json.Unmarshal(api.Result, &listResp.Certificates); err != nil {
+		return nil, err
+	}
 
-	resp, err := c.client.Do(r)
+	if api.ResultInfo != nil {
+		listResp.ResultInfo = *api.ResultInfo
+	}
+
+	return &listResp, nil
+}
+
+// Get returns the Origin CA certificate with the given ID.
+func (c *Client) Get(ctx context.Context, id string) (*Certificate, error) {
+	api, err := c.do(ctx, "get", "GET", c.endpoint+"/"+url.PathEscape(id), nil)
 	if err != nil {
 		return nil, err
 	}
+
+	cert := Certificate{}
+	if err := json.Unmarshal(api.Result, &cert); err != nil {
+		return nil, err
+	}
+
+	cert.RayID = api.RayID
+
+	return &cert, nil
+}
+
+// Revoke revokes the Origin CA certificate with the given ID.
+func (c *Client) Revoke(ctx context.Context, id string) (*RevokeResponse, error) {
+	api, err := c.do(ctx, "revoke", "DELETE", c.endpoint+"/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	revokeResp := RevokeResponse{}
+	if err := json.Unmarshal(api.Result, &revokeResp); err != nil {
+		return nil, err
+	}
+
+	return &revokeResp, nil
+}
+
+// do sends an authenticated request to the API and decodes the response
+// envelope, returning an *Error if the API did not report success. Failed
+// requests are retried according to the client's RetryPolicy. Every request is
+// recorded in the cfapi metrics under the name of its API endpoint.
+func (c *Client) do(ctx context.Context, name, method, endpoint string, body []byte) (api *APIResponse, err error) {
+	var (
+		resp  *http.Response
+		start = time.Now()
+	)
+
+	defer func() {
+		status, code := 0, 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+
+		var apiErr *Error
+		if errors.As(err, &apiErr) && len(apiErr.Errors) > 0 {
+			code = apiErr.Errors[0].Code
+		}
+
+		metrics.ObserveAPIRequest(name, status, code, time.Since(start))
+	}()
+
+	for attempt := 1; ; attempt++ {
+		resp, err = c.send(ctx, method, endpoint, body)
+		if attempt >= c.retry.MaxAttempts || !c.retry.retryable(method, resp, err) {
+			break
+		}
+
+		delay, ok := c.retry.backoff(attempt, resp)
+		if !ok || expires(ctx, delay) {
+			break
+		}
+
+		discard(resp)
+
+		if err := wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+
+	if err != nil {
+		return nil, &Error{Class: ErrorClassNetwork, Err: err}
+	}
 	defer resp.Body.Close()
 
 	rayID := resp.Header.Get("CF-Ray")
 
-	api := APIResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(&api); err != nil {
-		return nil, err
+	api = &APIResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(api); err != nil {
+		// Anything but a JSON envelope, such as an HTML error page from the
+		// edge, means the API itself never handled the request.
+		class := classify(resp.StatusCode, nil)
+		if class == ErrorClassUnknown {
+			class = ErrorClassServer
+		}
+
+		return nil, &Error{
+			StatusCode: resp.StatusCode,
+			RayID:      rayID,
+			Class:      class,
+			Err:        fmt.Errorf("unable to decode response: %w", err),
+		}
 	}
 
+	api.RayID = rayID
+
 	if !api.Success {
-		err := &api.Errors[0]
-		err.RayID = rayID
-		return nil, err
+		return nil, &Error{
+			StatusCode: resp.StatusCode,
+			Errors:     api.Errors,
+			RayID:      rayID,
+			Class:      classify(resp.StatusCode, api.Errors),
+		}
 	}
 
-	signResp := SignResponse{}
-	if err := json.Unmarshal(api.Result, &signResp); err != nil {
+	return api, nil
+}
+
+// send makes a single attempt at an authenticated request.
+func (c *Client) send(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
 		return nil, err
 	}
 
-	return &signResp, nil
+	r.Header.Add("User-Agent", "github.com/cloudflare/origin-ca-issuer")
+	if len(c.token) > 0 {
+		r.Header.Add("Authorization", "Bearer "+string(c.token))
+	} else {
+		r.Header.Add("X-Auth-User-Service-Key", string(c.serviceKey))
+	}
+
+	return c.client.Do(r)
 }
 
 // adapted from http://choly.ca/post/go-json-marshalling/
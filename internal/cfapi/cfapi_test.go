@@ -3,13 +3,17 @@ package cfapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/cloudflare/origin-ca-issuer/internal/metrics"
 	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestSignResponse_Unmarshal(t *testing.T) {
@@ -104,6 +108,7 @@ func TestSign(t *testing.T) {
 				Type:        "origin-ecc",
 				Validity:    7,
 				CSR:         "-----BEGIN CERTIFICATE REQUEST-----\n-----END CERTIFICATE REQUEST-----",
+				RayID:       "0123456789abcdef-ABC",
 			},
 			error: "",
 		},
@@ -154,6 +159,532 @@ func TestSign(t *testing.T) {
 
 }
 
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Options
+		header  string
+		value   string
+	}{
+		{
+			name:   "service key",
+			header: "X-Auth-User-Service-Key",
+			value:  "v1.0-FFFF-FFFF",
+		},
+		{
+			name:    "api token",
+			options: []Options{WithAPIToken([]byte("cloudflare"))},
+			header:  "Authorization",
+			value:   "Bearer cloudflare",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if diff := cmp.Diff(r.Header.Get(tt.header), tt.value); diff != "" {
+					t.Errorf("diff: (-want +got)\n%s", diff)
+				}
+
+				fmt.Fprintln(w, `{"success": true, "errors": [], "message": [], "result": []}`)
+			}))
+			defer ts.Close()
+
+			client := New([]byte("v1.0-FFFF-FFFF"),
+				append([]Options{WithClient(ts.Client()), Must(WithEndpoint(ts.URL))}, tt.options...)...,
+			)
+
+			if _, err := client.List(context.Background(), &ListRequest{}); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	expectedTime := time.Date(2020, time.December, 25, 6, 27, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		request  *ListRequest
+		handler  http.Handler
+		response *ListResponse
+		error    string
+	}{
+		{
+			name:    "API success",
+			request: &ListRequest{ZoneID: "023e105f4ecef8ad9ca31a8372d0c353", Page: 2, PerPage: 1},
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "GET" {
+					t.Errorf("expected GET, got %s", r.Method)
+				}
+				if diff := cmp.Diff(r.URL.RawQuery, "page=2&per_page=1&zone_id=023e105f4ecef8ad9ca31a8372d0c353"); diff != "" {
+					t.Errorf("diff: (-want +got)\n%s", diff)
+				}
+
+				w.Header().Add("cf-ray", "0123456789abcdef-ABC")
+				fmt.Fprintln(w, `{
+	"success": true,
+	"errors": [],
+	"message": [],
+	"result": [{
+		"id":"9001",
+		"certificate":"-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n",
+		"expires_on":"2020-12-25T06:27:00Z",
+		"request_type":"origin-ecc",
+		"hostnames":["example.com"],
+		"csr":"-----BEGIN CERTIFICATE REQUEST-----\n-----END CERTIFICATE REQUEST-----",
+		"requested_validity":7
+	}],
+	"result_info": {"page": 2, "per_page": 1, "count": 1, "total_count": 2, "total_pages": 2}
+}`)
+			}),
+			response: &ListResponse{
+				Certificates: []Certificate{
+					{
+						Id:          "9001",
+						Certificate: "-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n",
+						Hostnames:   []string{"example.com"},
+						Expiration:  expectedTime,
+						Type:        "origin-ecc",
+						Validity:    7,
+						CSR:         "-----BEGIN CERTIFICATE REQUEST-----\n-----END CERTIFICATE REQUEST-----",
+					},
+				},
+				ResultInfo: ResultInfo{
+					Page:       2,
+					PerPage:    1,
+					Count:      1,
+					TotalCount: 2,
+					TotalPages: 2,
+				},
+			},
+		},
+		{
+			name:    "API error",
+			request: &ListRequest{},
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("cf-ray", "0123456789abcdef-ABC")
+				fmt.Fprintln(w, `{
+	"success": false,
+	"errors": [{"code": 1010, "message": "zone_id is required"}],
+	"message": [],
+	"result": null
+}`)
+			}),
+			response: nil,
+			error:    "Cloudflare API Error code=1010 message=zone_id is required ray_id=0123456789abcdef-ABC",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewTLSServer(tt.handler)
+			defer ts.Close()
+
+			client := New([]byte("v1.0-FFFF-FFFF"),
+				WithClient(ts.Client()),
+				Must(WithEndpoint(ts.URL)),
+			)
+			resp, err := client.List(context.Background(), tt.request)
+
+			if diff := cmp.Diff(resp, tt.response); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+
+			if tt.error != "" {
+				if diff := cmp.Diff(err.Error(), tt.error); diff != "" {
+					t.Fatalf("diff: (-want +got)\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if diff := cmp.Diff(r.Method+" "+r.URL.Path, "GET /client/v4/certificates/9001"); diff != "" {
+			t.Errorf("diff: (-want +got)\n%s", diff)
+		}
+
+		fmt.Fprintln(w, `{
+	"success": true,
+	"errors": [],
+	"message": [],
+	"result": {
+		"id":"9001",
+		"certificate":"-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n",
+		"expires_on":"2020-12-25T06:27:00Z",
+		"request_type":"origin-ecc",
+		"hostnames":["example.com"],
+		"csr":"-----BEGIN CERTIFICATE REQUEST-----\n-----END CERTIFICATE REQUEST-----",
+		"requested_validity":7
+	}
+}`)
+	}))
+	defer ts.Close()
+
+	client := New([]byte("v1.0-FFFF-FFFF"),
+		WithClient(ts.Client()),
+		Must(WithEndpoint(ts.URL)),
+	)
+
+	resp, err := client.Get(context.Background(), "9001")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := &Certificate{
+		Id:          "9001",
+		Certificate: "-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n",
+		Hostnames:   []string{"example.com"},
+		Expiration:  time.Date(2020, time.December, 25, 6, 27, 0, 0, time.UTC),
+		Type:        "origin-ecc",
+		Validity:    7,
+		CSR:         "-----BEGIN CERTIFICATE REQUEST-----\n-----END CERTIFICATE REQUEST-----",
+	}
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if diff := cmp.Diff(r.Method+" "+r.URL.Path, "DELETE /client/v4/certificates/9001"); diff != "" {
+			t.Errorf("diff: (-want +got)\n%s", diff)
+		}
+
+		fmt.Fprintln(w, `{
+	"success": true,
+	"errors": [],
+	"message": [],
+	"result": {"id": "9001", "revoked_at": "2020-12-25T06:27:00Z"}
+}`)
+	}))
+	defer ts.Close()
+
+	client := New([]byte("v1.0-FFFF-FFFF"),
+		WithClient(ts.Client()),
+		Must(WithEndpoint(ts.URL)),
+	)
+
+	resp, err := client.Revoke(context.Background(), "9001")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := &RevokeResponse{
+		Id:        "9001",
+		RevokedAt: time.Date(2020, time.December, 25, 6, 27, 0, 0, time.UTC),
+	}
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+
+	success := `{"success": true, "errors": [], "message": [], "result": []}`
+	failure := `{"success": false, "errors": [{"code": 10000, "message": "Internal Error"}], "message": [], "result": null}`
+
+	tests := []struct {
+		name     string
+		method   string
+		timeout  time.Duration
+		statuses []int
+		headers  http.Header
+		attempts int
+		error    string
+	}{
+		{
+			name:     "rate limited sign",
+			method:   "POST",
+			statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			headers:  http.Header{"Retry-After": []string{"0"}},
+			attempts: 2,
+		},
+		{
+			name:     "server error sign is not retried",
+			method:   "POST",
+			statuses: []int{http.StatusInternalServerError, http.StatusOK},
+			attempts: 1,
+			error:    "Cloudflare API Error code=10000 message=Internal Error ray_id=",
+		},
+		{
+			name:     "server error list is retried",
+			method:   "GET",
+			statuses: []int{http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusOK},
+			attempts: 3,
+		},
+		{
+			name:     "attempts exhausted",
+			method:   "GET",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			attempts: 3,
+			error:    "Cloudflare API Error code=10000 message=Internal Error ray_id=",
+		},
+		{
+			name:     "client error is not retried",
+			method:   "GET",
+			statuses: []int{http.StatusBadRequest, http.StatusOK},
+			attempts: 1,
+			error:    "Cloudflare API Error code=10000 message=Internal Error ray_id=",
+		},
+		{
+			name:     "retry-after beyond max delay",
+			method:   "POST",
+			statuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			headers:  http.Header{"Retry-After": []string{"5"}},
+			attempts: 1,
+			error:    "Cloudflare API Error code=10000 message=Internal Error ray_id=",
+		},
+		{
+			name:     "retry-after beyond deadline",
+			method:   "GET",
+			timeout:  500 * time.Millisecond,
+			statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			headers:  http.Header{"Retry-After": []string{"1"}},
+			attempts: 1,
+			error:    "Cloudflare API Error code=10000 message=Internal Error ray_id=",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[attempts]
+				attempts++
+
+				for k, v := range tt.headers {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(status)
+
+				if status == http.StatusOK {
+					fmt.Fprintln(w, success)
+				} else {
+					fmt.Fprintln(w, failure)
+				}
+			}))
+			defer ts.Close()
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			client := New([]byte("v1.0-FFFF-FFFF"),
+				WithClient(ts.Client()),
+				Must(WithEndpoint(ts.URL)),
+				WithRetryPolicy(policy),
+			)
+
+			_, err := client.do(ctx, "test", tt.method, client.endpoint, []byte("{}"))
+			if tt.error != "" {
+				if err == nil {
+					t.Fatalf("expected error %q", tt.error)
+				}
+				if diff := cmp.Diff(err.Error(), tt.error); diff != "" {
+					t.Fatalf("diff: (-want +got)\n%s", diff)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(attempts, tt.attempts); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		class  ErrorClass
+		error  string
+	}{
+		{
+			name:   "empty errors",
+			status: http.StatusOK,
+			body:   `{"success": false, "errors": [], "messages": [], "result": null}`,
+			class:  ErrorClassUnknown,
+			error:  "Cloudflare API Error status=200 ray_id=0123456789abcdef-ABC",
+		},
+		{
+			name:   "html from the edge",
+			status: http.StatusBadGateway,
+			body:   `<html><body>502 Bad Gateway</body></html>`,
+			class:  ErrorClassServer,
+			error:  "Cloudflare API Error status=502 ray_id=0123456789abcdef-ABC: unable to decode response: invalid character '<' looking for beginning of value",
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+			body:   `{"success": false, "errors": [{"code": 9109, "message": "Invalid access token"}], "messages": [], "result": null}`,
+			class:  ErrorClassAuth,
+			error:  "Cloudflare API Error code=9109 message=Invalid access token ray_id=0123456789abcdef-ABC",
+		},
+		{
+			name:   "authentication error code",
+			status: http.StatusOK,
+			body:   `{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}], "messages": [], "result": null}`,
+			class:  ErrorClassAuth,
+			error:  "Cloudflare API Error code=10000 message=Authentication error ray_id=0123456789abcdef-ABC",
+		},
+		{
+			name:   "rate limited",
+			status: http.StatusTooManyRequests,
+			body:   `{"success": false, "errors": [{"code": 971, "message": "Please wait and consider throttling your request speed"}], "messages": [], "result": null}`,
+			class:  ErrorClassRateLimited,
+			error:  "Cloudflare API Error code=971 message=Please wait and consider throttling your request speed ray_id=0123456789abcdef-ABC",
+		},
+		{
+			name:   "multiple validation errors",
+			status: http.StatusBadRequest,
+			body:   `{"success": false, "errors": [{"code": 1010, "message": "Failed to validate CSR"}, {"code": 1011, "message": "Invalid hostnames"}], "messages": [], "result": null}`,
+			class:  ErrorClassValidation,
+			error:  "Cloudflare API Error code=1010 message=Failed to validate CSR; code=1011 message=Invalid hostnames ray_id=0123456789abcdef-ABC",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("cf-ray", "0123456789abcdef-ABC")
+				w.WriteHeader(tt.status)
+				fmt.Fprintln(w, tt.body)
+			}))
+			defer ts.Close()
+
+			client := New([]byte("v1.0-FFFF-FFFF"),
+				WithClient(ts.Client()),
+				Must(WithEndpoint(ts.URL)),
+			)
+
+			_, err := client.Sign(context.Background(), &SignRequest{})
+
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *Error, got %T: %v", err, err)
+			}
+
+			if diff := cmp.Diff(apiErr.StatusCode, tt.status); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+
+			if diff := cmp.Diff(ClassOf(err), tt.class); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+
+			if diff := cmp.Diff(err.Error(), tt.error); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestErrors_Network(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	client := New([]byte("v1.0-FFFF-FFFF"),
+		WithClient(ts.Client()),
+		Must(WithEndpoint(ts.URL)),
+	)
+	ts.Close()
+
+	_, err := client.Sign(context.Background(), &SignRequest{})
+	if !IsNetwork(err) || !IsTransient(err) {
+		t.Fatalf("expected a transient network error, got %v", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		code   string
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"success": true, "errors": [], "messages": [], "result": {"id": "1", "expires_on": "2014-01-01T05:20:00Z"}}`,
+			code:   "none",
+		},
+		{
+			name:   "api error",
+			status: http.StatusForbidden,
+			body:   `{"success": false, "errors": [{"code": 9109, "message": "Invalid access token"}], "messages": [], "result": null}`,
+			code:   "9109",
+		},
+		{
+			name:   "undecodable",
+			status: http.StatusBadGateway,
+			body:   `<html><body>502 Bad Gateway</body></html>`,
+			code:   "none",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprintln(w, tt.body)
+			}))
+			defer ts.Close()
+
+			client := New([]byte("v1.0-FFFF-FFFF"),
+				WithClient(ts.Client()),
+				Must(WithEndpoint(ts.URL)),
+			)
+
+			counter := metrics.APIRequests.WithLabelValues("sign", strconv.Itoa(tt.status), tt.code)
+			before := testutil.ToFloat64(counter)
+
+			_, _ = client.Sign(context.Background(), &SignRequest{})
+
+			if diff := cmp.Diff(testutil.ToFloat64(counter)-before, 1.0); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{value: "", ok: false},
+		{value: "120", expected: 2 * time.Minute, ok: true},
+		{value: "-1", ok: false},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", expected: 0, ok: true},
+		{value: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		d, ok := retryAfter(tt.value)
+		if d != tt.expected || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %t; want %s, %t", tt.value, d, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
 func Must(opt Options, err error) Options {
 	if err != nil {
 		panic("option constructo returned error " + err.Error())
@@ -0,0 +1,180 @@
+package cfapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorClass broadly categorises why a request to the Cloudflare API failed,
+// so callers can decide how to react without inspecting API error codes.
+type ErrorClass string
+
+const (
+	// ErrorClassUnknown is used when the failure could not be categorised.
+	ErrorClassUnknown ErrorClass = "Unknown"
+
+	// ErrorClassAuth means the credentials were missing, invalid, revoked, or
+	// lack the required permissions.
+	ErrorClassAuth ErrorClass = "Auth"
+
+	// ErrorClassValidation means the API rejected the request itself, such as
+	// an invalid CSR or hostname. Repeating the request will not succeed.
+	ErrorClassValidation ErrorClass = "Validation"
+
+	// ErrorClassRateLimited means the API refused the request because too many
+	// requests were made.
+	ErrorClassRateLimited ErrorClass = "RateLimited"
+
+	// ErrorClassServer means the API, or the Cloudflare edge in front of it,
+	// failed to handle the request.
+	ErrorClassServer ErrorClass = "Server"
+
+	// ErrorClassNetwork means no response was received from the API.
+	ErrorClassNetwork ErrorClass = "Network"
+)
+
+// authErrorCodes are API error codes returned for authentication and
+// authorization failures, which may be sent with a 200 or 400 status.
+var authErrorCodes = map[int]bool{
+	9103:  true, // Unknown X-Auth-Key or X-Auth-Email
+	9106:  true, // Missing X-Auth-Key, X-Auth-Email or Authorization headers
+	9107:  true, // Missing X-Auth-Key, X-Auth-Email or Authorization headers
+	9109:  true, // Invalid access token
+	10000: true, // Authentication error
+	10001: true, // Unable to authenticate request
+}
+
+// Error is returned by Client for any request that did not succeed. It carries
+// everything known about the failure: the HTTP status, every error reported by
+// the API, the CF-Ray ID to quote to Cloudflare support, and a classification.
+type Error struct {
+	// StatusCode is the HTTP status of the response, or zero if no response
+	// was received.
+	StatusCode int
+
+	// Errors are the errors reported by the API, if the response body could
+	// be decoded.
+	Errors []APIError
+
+	// RayID is the CF-Ray ID of the response.
+	RayID string
+
+	// Class categorises the failure.
+	Class ErrorClass
+
+	// Err is the underlying error, if the failure was not reported by the API
+	// itself, such as a transport error or an undecodable response.
+	Err error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case len(e.Errors) == 1:
+		err := e.Errors[0]
+		err.RayID = e.RayID
+		return err.Error()
+	case len(e.Errors) > 1:
+		msgs := make([]string, 0, len(e.Errors))
+		for _, err := range e.Errors {
+			msgs = append(msgs, fmt.Sprintf("code=%d message=%s", err.Code, err.Message))
+		}
+		return fmt.Sprintf("Cloudflare API Error %s ray_id=%s", strings.Join(msgs, "; "), e.RayID)
+	case e.Err != nil && e.StatusCode == 0:
+		return fmt.Sprintf("Cloudflare API Error: %s", e.Err)
+	case e.Err != nil:
+		return fmt.Sprintf("Cloudflare API Error status=%d ray_id=%s: %s", e.StatusCode, e.RayID, e.Err)
+	default:
+		return fmt.Sprintf("Cloudflare API Error status=%d ray_id=%s", e.StatusCode, e.RayID)
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// classify categorises a failed response from its HTTP status and, for
+// responses without a telling status, the API error codes.
+func classify(status int, errs []APIError) ErrorClass {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorClassAuth
+	case status == http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case status >= 500:
+		return ErrorClassServer
+	}
+
+	for _, err := range errs {
+		if authErrorCodes[err.Code] {
+			return ErrorClassAuth
+		}
+	}
+
+	switch {
+	case status >= 400:
+		return ErrorClassValidation
+	case len(errs) > 0:
+		return ErrorClassValidation
+	}
+
+	return ErrorClassUnknown
+}
+
+// ClassOf returns the classification of a Cloudflare API error, or
+// ErrorClassUnknown if err is not an *Error.
+func ClassOf(err error) ErrorClass {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Class
+	}
+
+	return ErrorClassUnknown
+}
+
+// RayIDOf returns the CF-Ray ID of a Cloudflare API error, if any.
+func RayIDOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RayID
+	}
+
+	return ""
+}
+
+// IsAuth reports whether err is a Cloudflare API authentication error.
+func IsAuth(err error) bool {
+	return ClassOf(err) == ErrorClassAuth
+}
+
+// IsValidation reports whether err is the Cloudflare API rejecting a request.
+func IsValidation(err error) bool {
+	return ClassOf(err) == ErrorClassValidation
+}
+
+// IsRateLimited reports whether err is the Cloudflare API rate limiting requests.
+func IsRateLimited(err error) bool {
+	return ClassOf(err) == ErrorClassRateLimited
+}
+
+// IsServer reports whether err is a Cloudflare API server error.
+func IsServer(err error) bool {
+	return ClassOf(err) == ErrorClassServer
+}
+
+// IsNetwork reports whether err is a failure to reach the Cloudflare API.
+func IsNetwork(err error) bool {
+	return ClassOf(err) == ErrorClassNetwork
+}
+
+// IsTransient reports whether err is a Cloudflare API error that may succeed
+// if the request is made again later.
+func IsTransient(err error) bool {
+	switch ClassOf(err) {
+	case ErrorClassRateLimited, ErrorClassServer, ErrorClassNetwork:
+		return true
+	}
+
+	return false
+}
@@ -1,11 +1,18 @@
 package cfapi
 
+// Credentials authenticate a client with the Cloudflare API. Only one of
+// ServiceKey or APIToken is expected to be set.
+type Credentials struct {
+	ServiceKey []byte
+	APIToken   []byte
+}
+
 type Factory interface {
-	APIWith([]byte) (Interface, error)
+	APIWith(Credentials) (Interface, error)
 }
 
-type FactoryFunc func([]byte) (Interface, error)
+type FactoryFunc func(Credentials) (Interface, error)
 
-func (f FactoryFunc) APIWith(serviceKey []byte) (Interface, error) {
-	return f(serviceKey)
+func (f FactoryFunc) APIWith(creds Credentials) (Interface, error) {
+	return f(creds)
 }
@@ -0,0 +1,128 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+)
+
+// A Fault scripts a failure for upcoming requests. Faults are consumed in the
+// order they were injected, each failing the requests it matches until its
+// count is exhausted.
+type Fault struct {
+	// Method restricts the fault to requests with the HTTP method, such as
+	// POST for signing requests. Empty matches any method.
+	Method string `json:"method,omitempty"`
+
+	// Count is the number of requests to fail. Zero fails a single request.
+	Count int `json:"count,omitempty"`
+
+	// Status is the HTTP status to respond with.
+	Status int `json:"status,omitempty"`
+
+	// Errors are returned in the API response envelope.
+	Errors []cfapi.APIError `json:"errors,omitempty"`
+
+	// Header is added to the response, such as a Retry-After header.
+	Header http.Header `json:"header,omitempty"`
+
+	// Body replaces the API response envelope, such as an HTML error page.
+	Body string `json:"body,omitempty"`
+
+	// Drop closes the connection without responding, simulating a network
+	// failure.
+	Drop bool `json:"drop,omitempty"`
+}
+
+// Inject queues faults for upcoming requests.
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range faults {
+		if f.Count <= 0 {
+			f.Count = 1
+		}
+		if f.Status == 0 {
+			f.Status = http.StatusInternalServerError
+		}
+
+		s.faults = append(s.faults, f)
+	}
+}
+
+// ResetFaults removes all pending faults.
+func (s *Server) ResetFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// fault writes the response of the first fault matching the request, if any,
+// and reports whether the request was handled.
+func (s *Server) fault(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	var f *Fault
+	for i := range s.faults {
+		if s.faults[i].Method == "" || s.faults[i].Method == r.Method {
+			fault := s.faults[i]
+			f = &fault
+
+			s.faults[i].Count--
+			if s.faults[i].Count == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if f == nil {
+		return false
+	}
+
+	if f.Drop {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+
+		panic(http.ErrAbortHandler)
+	}
+
+	for k, v := range f.Header {
+		w.Header()[http.CanonicalHeaderKey(k)] = v
+	}
+
+	if f.Body != "" {
+		w.Header().Set("CF-Ray", rayID())
+		w.WriteHeader(f.Status)
+		_, _ = w.Write([]byte(f.Body))
+
+		return true
+	}
+
+	errs := f.Errors
+	if len(errs) == 0 {
+		errs = []cfapi.APIError{{Code: 10001, Message: http.StatusText(f.Status)}}
+	}
+	writeError(w, f.Status, errs...)
+
+	return true
+}
+
+// UnmarshalFaults decodes a JSON array of faults, as used by the
+// fake-origin-ca command.
+func UnmarshalFaults(p []byte) ([]Fault, error) {
+	var faults []Fault
+	if err := json.Unmarshal(p, &faults); err != nil {
+		return nil, err
+	}
+
+	return faults, nil
+}
@@ -0,0 +1,493 @@
+// Package fake implements an in-memory Origin CA API, backed by a locally
+// generated root certificate authority. It signs CSRs the same way the
+// Cloudflare API does, so the issuer can be exercised end to end without a
+// Cloudflare account.
+package fake
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// Path is the path the Origin CA API is served from.
+	Path = "/client/v4/certificates"
+
+	defaultPerPage = 20
+	maxPerPage     = 50
+)
+
+// API error codes returned by the fake. Only the authentication code matches
+// the Cloudflare API; the others are stable values for tests to match on.
+const (
+	CodeAuthentication     = 10000
+	CodeInvalidRequest     = 1001
+	CodeNotFound           = 1004
+	CodeInvalidCSR         = 1010
+	CodeInvalidHostnames   = 1011
+	CodeInvalidValidity    = 1012
+	CodeInvalidRequestType = 1013
+	CodeInvalidZone        = 7003
+)
+
+var allowedValidity = map[int]bool{7: true, 30: true, 90: true, 365: true, 730: true, 1095: true, 5475: true}
+
+// Server is an http.Handler implementing the Origin CA certificates API. Use it
+// with httptest.NewServer, or serve it directly.
+type Server struct {
+	clock       clock.Clock
+	credentials map[string]bool
+	zones       map[string]string
+
+	root    *x509.Certificate
+	rootPEM []byte
+	rootKey crypto.Signer
+
+	mu      sync.Mutex
+	certs   map[string]*record
+	order   []string
+	faults  []Fault
+	signed  int
+	revoked int
+}
+
+type record struct {
+	cert      cfapi.Certificate
+	revokedAt *time.Time
+}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithClock sets the clock used for certificate validity and revocation times.
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) {
+		s.clock = c
+	}
+}
+
+// WithCredentials restricts the service keys and API tokens the server accepts.
+// By default any non-empty credential is accepted.
+func WithCredentials(credentials ...string) Option {
+	return func(s *Server) {
+		for _, c := range credentials {
+			s.credentials[c] = true
+		}
+	}
+}
+
+// WithZone registers a zone, so certificates can be listed by zone ID. A
+// certificate belongs to a zone if any of its hostnames is within the zone.
+func WithZone(id, name string) Option {
+	return func(s *Server) {
+		s.zones[id] = strings.ToLower(name)
+	}
+}
+
+// New returns a Server with a freshly generated root certificate authority.
+func New(options ...Option) (*Server, error) {
+	s := &Server{
+		clock:       clock.RealClock{},
+		credentials: map[string]bool{},
+		zones:       map[string]string{},
+		certs:       map[string]*record{},
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating root key: %w", err)
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization:       []string{"Fake Origin CA"},
+			OrganizationalUnit: []string{"origin-ca-issuer"},
+			CommonName:         "Fake Origin CA Root",
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(20, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("creating root certificate: %w", err)
+	}
+
+	s.root, err = x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	s.rootPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	s.rootKey = key
+
+	return s, nil
+}
+
+// Root returns the root certificate authority that signs all certificates.
+func (s *Server) Root() *x509.Certificate {
+	return s.root
+}
+
+// RootPEM returns the PEM encoded root certificate authority.
+func (s *Server) RootPEM() []byte {
+	return s.rootPEM
+}
+
+// Certificates returns every certificate issued by the server, including
+// revoked certificates, in the order they were issued.
+func (s *Server) Certificates() []cfapi.Certificate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	certs := make([]cfapi.Certificate, 0, len(s.order))
+	for _, id := range s.order {
+		certs = append(certs, s.certs[id].cert)
+	}
+
+	return certs
+}
+
+// Revoked reports whether the certificate with the given ID has been revoked.
+func (s *Server) Revoked(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.certs[id]
+
+	return ok && r.revokedAt != nil
+}
+
+// Counts returns how many certificates have been signed and revoked.
+func (s *Server) Counts() (signed, revoked int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.signed, s.revoked
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.fault(w, r) {
+		return
+	}
+
+	if !s.authenticated(r) {
+		writeError(w, http.StatusForbidden, cfapi.APIError{Code: CodeAuthentication, Message: "Authentication error"})
+		return
+	}
+
+	id, hasID := strings.CutPrefix(r.URL.Path, Path+"/")
+	switch {
+	case r.URL.Path == Path && r.Method == http.MethodPost:
+		s.sign(w, r)
+	case r.URL.Path == Path && r.Method == http.MethodGet:
+		s.list(w, r)
+	case hasID && id != "" && r.Method == http.MethodGet:
+		s.get(w, id)
+	case hasID && id != "" && r.Method == http.MethodDelete:
+		s.revoke(w, id)
+	default:
+		writeError(w, http.StatusNotFound, cfapi.APIError{Code: 7000, Message: "No route for that URI"})
+	}
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	credential := r.Header.Get("X-Auth-User-Service-Key")
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		credential = token
+	}
+
+	if credential == "" {
+		return false
+	}
+
+	return len(s.credentials) == 0 || s.credentials[credential]
+}
+
+func (s *Server) sign(w http.ResponseWriter, r *http.Request) {
+	req := cfapi.SignRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidRequest, Message: fmt.Sprintf("Invalid request: %s", err)})
+		return
+	}
+
+	block, _ := pem.Decode([]byte(req.CSR))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidCSR, Message: "Failed to decode CSR"})
+		return
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidCSR, Message: fmt.Sprintf("Failed to parse CSR: %s", err)})
+		return
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidCSR, Message: fmt.Sprintf("Failed to validate CSR signature: %s", err)})
+		return
+	}
+
+	switch {
+	case req.Type == "origin-rsa" && csr.PublicKeyAlgorithm == x509.RSA:
+	case req.Type == "origin-ecc" && csr.PublicKeyAlgorithm == x509.ECDSA:
+	case req.Type == "origin-rsa" || req.Type == "origin-ecc":
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidRequestType, Message: fmt.Sprintf("CSR key algorithm %s does not match request type %s", csr.PublicKeyAlgorithm, req.Type)})
+		return
+	default:
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidRequestType, Message: fmt.Sprintf("Invalid request type %q", req.Type)})
+		return
+	}
+
+	if !allowedValidity[req.Validity] {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidValidity, Message: fmt.Sprintf("Invalid requested validity %d", req.Validity)})
+		return
+	}
+
+	if len(req.Hostnames) == 0 {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidHostnames, Message: "At least one hostname is required"})
+		return
+	}
+
+	for _, h := range req.Hostnames {
+		if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(h, "*.")); len(errs) > 0 {
+			writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidHostnames, Message: fmt.Sprintf("Invalid hostname %q: %s", h, strings.Join(errs, ", "))})
+			return
+		}
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, cfapi.APIError{Code: 10001, Message: err.Error()})
+		return
+	}
+
+	now := s.clock.Now().UTC().Truncate(time.Second)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization:       []string{"Fake Origin CA"},
+			OrganizationalUnit: []string{"origin-ca-issuer"},
+			CommonName:         "Fake Origin Certificate",
+		},
+		DNSNames:    req.Hostnames,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.AddDate(0, 0, req.Validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, s.root, csr.PublicKey, s.rootKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, cfapi.APIError{Code: 10001, Message: err.Error()})
+		return
+	}
+
+	cert := cfapi.Certificate{
+		Id:          serial.String(),
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Hostnames:   req.Hostnames,
+		Expiration:  tmpl.NotAfter,
+		Type:        req.Type,
+		Validity:    req.Validity,
+		CSR:         req.CSR,
+	}
+
+	s.mu.Lock()
+	s.certs[cert.Id] = &record{cert: cert}
+	s.order = append(s.order, cert.Id)
+	s.signed++
+	s.mu.Unlock()
+
+	writeResult(w, cert, nil)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	page, err := positive(q.Get("page"), 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidRequest, Message: fmt.Sprintf("Invalid page: %s", err)})
+		return
+	}
+
+	perPage, err := positive(q.Get("per_page"), defaultPerPage)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidRequest, Message: fmt.Sprintf("Invalid per_page: %s", err)})
+		return
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	zone := ""
+	if id := q.Get("zone_id"); id != "" {
+		var ok bool
+		if zone, ok = s.zones[id]; !ok {
+			writeError(w, http.StatusBadRequest, cfapi.APIError{Code: CodeInvalidZone, Message: "Could not route to zone, perhaps your object identifier is invalid?"})
+			return
+		}
+	}
+
+	s.mu.Lock()
+	certs := []cfapi.Certificate{}
+	for _, id := range s.order {
+		rec := s.certs[id]
+		if rec.revokedAt == nil && (zone == "" || inZone(rec.cert.Hostnames, zone)) {
+			certs = append(certs, rec.cert)
+		}
+	}
+	s.mu.Unlock()
+
+	info := &cfapi.ResultInfo{
+		Page:       page,
+		PerPage:    perPage,
+		TotalCount: len(certs),
+		TotalPages: (len(certs) + perPage - 1) / perPage,
+	}
+
+	start := min((page-1)*perPage, len(certs))
+	end := min(start+perPage, len(certs))
+	certs = certs[start:end]
+	info.Count = len(certs)
+
+	writeResult(w, certs, info)
+}
+
+func (s *Server) get(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	rec, ok := s.certs[id]
+	s.mu.Unlock()
+
+	if !ok || rec.revokedAt != nil {
+		writeError(w, http.StatusNotFound, cfapi.APIError{Code: CodeNotFound, Message: "Failed to read certificate"})
+		return
+	}
+
+	writeResult(w, rec.cert, nil)
+}
+
+func (s *Server) revoke(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.certs[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, cfapi.APIError{Code: CodeNotFound, Message: "Failed to read certificate"})
+		return
+	}
+
+	if rec.revokedAt == nil {
+		now := s.clock.Now().UTC()
+		rec.revokedAt = &now
+		s.revoked++
+	}
+
+	writeResult(w, cfapi.RevokeResponse{Id: id, RevokedAt: *rec.revokedAt}, nil)
+}
+
+func inZone(hostnames []string, zone string) bool {
+	for _, h := range hostnames {
+		h = strings.ToLower(strings.TrimPrefix(h, "*."))
+		if h == zone || strings.HasSuffix(h, "."+zone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func positive(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 1 {
+		return 0, fmt.Errorf("%d must be positive", n)
+	}
+
+	return n, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	return serial, nil
+}
+
+func writeResult(w http.ResponseWriter, result interface{}, info *cfapi.ResultInfo) {
+	p, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, cfapi.APIError{Code: 10001, Message: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cfapi.APIResponse{
+		Success:    true,
+		Errors:     []cfapi.APIError{},
+		Messages:   []string{},
+		Result:     p,
+		ResultInfo: info,
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, errs ...cfapi.APIError) {
+	writeJSON(w, status, cfapi.APIResponse{
+		Success:  false,
+		Errors:   errs,
+		Messages: []string{},
+		Result:   json.RawMessage("null"),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("CF-Ray", rayID())
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func rayID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+
+	return fmt.Sprintf("%x-FAKE", b)
+}
@@ -0,0 +1,252 @@
+package fake
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	"gotest.tools/v3/assert"
+	fakeclock "k8s.io/utils/clock/testing"
+)
+
+func newClient(t *testing.T, s *Server, options ...cfapi.Options) *cfapi.Client {
+	t.Helper()
+
+	ts := httptest.NewTLSServer(s)
+	t.Cleanup(ts.Close)
+
+	endpoint, err := cfapi.WithEndpoint(ts.URL)
+	assert.NilError(t, err)
+
+	return cfapi.New([]byte("v1.0-FFFF-FFFF"), append([]cfapi.Options{cfapi.WithClient(ts.Client()), endpoint}, options...)...)
+}
+
+func csr(t *testing.T, alg x509.PublicKeyAlgorithm, hostnames ...string) string {
+	t.Helper()
+
+	p, _, err := cmgen.CSR(alg, cmgen.SetCSRDNSNames(hostnames...))
+	assert.NilError(t, err)
+
+	return string(p)
+}
+
+func TestSign(t *testing.T) {
+	clock := fakeclock.NewFakeClock(time.Date(2020, time.December, 25, 6, 27, 0, 0, time.UTC))
+
+	s, err := New(WithClock(clock))
+	assert.NilError(t, err)
+
+	client := newClient(t, s)
+
+	resp, err := client.Sign(context.Background(), &cfapi.SignRequest{
+		Hostnames: []string{"example.com", "*.example.com"},
+		Validity:  90,
+		Type:      "origin-ecc",
+		CSR:       csr(t, x509.ECDSA, "example.com"),
+	})
+	assert.NilError(t, err)
+
+	block, _ := pem.Decode([]byte(resp.Certificate))
+	assert.Assert(t, block != nil)
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NilError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Root())
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:     "www.example.com",
+		Roots:       roots,
+		CurrentTime: clock.Now(),
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, cert.DNSNames, []string{"example.com", "*.example.com"})
+	assert.Equal(t, cert.NotAfter, clock.Now().AddDate(0, 0, 90))
+	assert.Equal(t, resp.Expiration, clock.Now().AddDate(0, 0, 90))
+	assert.Equal(t, resp.Id, cert.SerialNumber.String())
+}
+
+func TestSign_Rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *cfapi.SignRequest
+		code int
+	}{
+		{
+			name: "invalid csr",
+			req:  &cfapi.SignRequest{Hostnames: []string{"example.com"}, Validity: 7, Type: "origin-ecc", CSR: "Lorem ipsum"},
+			code: CodeInvalidCSR,
+		},
+		{
+			name: "mismatched request type",
+			req:  &cfapi.SignRequest{Hostnames: []string{"example.com"}, Validity: 7, Type: "origin-rsa", CSR: csr(t, x509.ECDSA, "example.com")},
+			code: CodeInvalidRequestType,
+		},
+		{
+			name: "invalid validity",
+			req:  &cfapi.SignRequest{Hostnames: []string{"example.com"}, Validity: 10, Type: "origin-ecc", CSR: csr(t, x509.ECDSA, "example.com")},
+			code: CodeInvalidValidity,
+		},
+		{
+			name: "no hostnames",
+			req:  &cfapi.SignRequest{Validity: 7, Type: "origin-ecc", CSR: csr(t, x509.ECDSA)},
+			code: CodeInvalidHostnames,
+		},
+	}
+
+	s, err := New()
+	assert.NilError(t, err)
+
+	client := newClient(t, s)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Sign(context.Background(), tt.req)
+
+			assert.Assert(t, cfapi.IsValidation(err), "expected validation error, got %v", err)
+			assert.ErrorContains(t, err, "code=")
+
+			var apiErr *cfapi.Error
+			assert.Assert(t, errors.As(err, &apiErr))
+			assert.Equal(t, apiErr.Errors[0].Code, tt.code)
+		})
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s, err := New(WithCredentials("secret-token"))
+	assert.NilError(t, err)
+
+	_, err = newClient(t, s).List(context.Background(), &cfapi.ListRequest{})
+	assert.Assert(t, cfapi.IsAuth(err), "expected auth error, got %v", err)
+
+	_, err = newClient(t, s, cfapi.WithAPIToken([]byte("secret-token"))).List(context.Background(), &cfapi.ListRequest{})
+	assert.NilError(t, err)
+}
+
+func TestLifecycle(t *testing.T) {
+	s, err := New(WithZone("023e105f4ecef8ad9ca31a8372d0c353", "example.com"))
+	assert.NilError(t, err)
+
+	client := newClient(t, s)
+	ctx := context.Background()
+
+	var ids []string
+	for _, h := range []string{"a.example.com", "b.example.com", "example.net"} {
+		resp, err := client.Sign(ctx, &cfapi.SignRequest{
+			Hostnames: []string{h},
+			Validity:  7,
+			Type:      "origin-rsa",
+			CSR:       csr(t, x509.RSA, h),
+		})
+		assert.NilError(t, err)
+
+		ids = append(ids, resp.Id)
+	}
+
+	list, err := client.List(ctx, &cfapi.ListRequest{ZoneID: "023e105f4ecef8ad9ca31a8372d0c353", Page: 2, PerPage: 1})
+	assert.NilError(t, err)
+	assert.Equal(t, len(list.Certificates), 1)
+	assert.Equal(t, list.Certificates[0].Id, ids[1])
+	assert.DeepEqual(t, list.ResultInfo, cfapi.ResultInfo{Page: 2, PerPage: 1, Count: 1, TotalCount: 2, TotalPages: 2})
+
+	_, err = client.List(ctx, &cfapi.ListRequest{ZoneID: "unknown"})
+	assert.Assert(t, cfapi.IsValidation(err))
+
+	cert, err := client.Get(ctx, ids[2])
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cert.Hostnames, []string{"example.net"})
+
+	revoked, err := client.Revoke(ctx, ids[2])
+	assert.NilError(t, err)
+	assert.Equal(t, revoked.Id, ids[2])
+	assert.Assert(t, s.Revoked(ids[2]))
+
+	_, err = client.Get(ctx, ids[2])
+	assert.Assert(t, cfapi.IsValidation(err))
+
+	list, err = client.List(ctx, &cfapi.ListRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, list.ResultInfo.TotalCount, 2)
+
+	signed, revokedCount := s.Counts()
+	assert.Equal(t, signed, 3)
+	assert.Equal(t, revokedCount, 1)
+}
+
+func TestFaults(t *testing.T) {
+	s, err := New()
+	assert.NilError(t, err)
+
+	client := newClient(t, s)
+	ctx := context.Background()
+
+	s.Inject(
+		Fault{Method: http.MethodPost, Status: http.StatusBadGateway, Body: "<html>502 Bad Gateway</html>"},
+		Fault{Status: http.StatusTooManyRequests, Count: 2},
+		Fault{Method: http.MethodPost, Drop: true},
+	)
+
+	_, err = client.Sign(ctx, &cfapi.SignRequest{})
+	assert.Assert(t, cfapi.IsServer(err), "expected server error, got %v", err)
+
+	for i := 0; i < 2; i++ {
+		_, err = client.List(ctx, &cfapi.ListRequest{})
+		assert.Assert(t, cfapi.IsRateLimited(err), "expected rate limited error, got %v", err)
+	}
+
+	// The transport transparently retries idempotent requests on a dropped
+	// connection, so only a signing request observes the failure.
+	_, err = client.Sign(ctx, &cfapi.SignRequest{})
+	assert.Assert(t, cfapi.IsNetwork(err), "expected network error, got %v", err)
+
+	_, err = client.List(ctx, &cfapi.ListRequest{})
+	assert.NilError(t, err)
+}
+
+func TestFaults_Retried(t *testing.T) {
+	s, err := New()
+	assert.NilError(t, err)
+
+	client := newClient(t, s, cfapi.WithRetryPolicy(cfapi.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Second,
+	}))
+
+	s.Inject(Fault{Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"0"}}, Count: 2})
+
+	_, err = client.Sign(context.Background(), &cfapi.SignRequest{
+		Hostnames: []string{"example.com"},
+		Validity:  7,
+		Type:      "origin-ecc",
+		CSR:       csr(t, x509.ECDSA, "example.com"),
+	})
+	assert.NilError(t, err)
+
+	signed, _ := s.Counts()
+	assert.Equal(t, signed, 1)
+}
+
+func TestUnmarshalFaults(t *testing.T) {
+	faults, err := UnmarshalFaults([]byte(`[
+  {"method": "POST", "status": 429, "count": 3, "header": {"Retry-After": ["1"]}},
+  {"drop": true}
+]`))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, faults, []Fault{
+		{Method: "POST", Status: 429, Count: 3, Header: http.Header{"Retry-After": []string{"1"}}},
+		{Drop: true},
+	})
+}
@@ -0,0 +1,164 @@
+package cfapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how a Client retries requests that failed in a way
+// that is safe to repeat. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a request,
+	// including the first one.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the second attempt. It doubles for
+	// every subsequent attempt.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff between attempts. Requests are not retried if
+	// the API asks for a longer delay with a Retry-After header.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is suitable for riding out short Cloudflare API incidents
+// without holding a reconcile for too long.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// WithRetryPolicy retries failed requests according to the provided policy.
+// Retries never outlive the deadline of the request's context.
+func WithRetryPolicy(policy RetryPolicy) Options {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// retryable reports whether a request may be sent again after it failed with
+// the given response or transport error.
+//
+// A 429 or 503 means the API did not act on the request, so it is always safe
+// to retry. Other server errors and transport errors leave the outcome unknown,
+// and are only retried for idempotent methods; retrying a signing request could
+// otherwise mint a second certificate.
+func (p RetryPolicy) retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+
+		return idempotent(method)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+
+	return false
+}
+
+// backoff returns how long to wait before the next attempt, or false if the
+// request should not be retried. A Retry-After header takes precedence over the
+// exponential backoff, which uses full jitter; if the API asks for a longer
+// delay than MaxDelay the request is not retried, rather than retried early.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return d, p.MaxDelay <= 0 || d <= p.MaxDelay
+		}
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = p.cap(d)
+
+	if d <= 0 {
+		return 0, true
+	}
+
+	return time.Duration(rand.Int63n(int64(d) + 1)), true
+}
+
+func (p RetryPolicy) cap(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+
+	return d
+}
+
+// retryAfter parses a Retry-After header, which is either a number of seconds
+// or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+
+		return d, true
+	}
+
+	return 0, false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
+
+// expires reports whether the context's deadline passes before d elapses.
+func expires(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+
+	return ok && time.Now().Add(d).After(deadline)
+}
+
+// wait blocks for the given delay, returning the context's error if it is
+// done first.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// discard drains and closes a response body so the connection can be reused.
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
@@ -2,14 +2,60 @@ package testingcfapi
 
 import (
 	"context"
+	"time"
 
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
 )
 
 type FakeClient struct {
-	Response *cfapi.SignResponse
+	Response     *cfapi.SignResponse
+	Err          error
+	ListErr      error
+	Certificates []cfapi.Certificate
 }
 
 func (f *FakeClient) Sign(context.Context, *cfapi.SignRequest) (*cfapi.SignResponse, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
 	return f.Response, nil
 }
+
+func (f *FakeClient) List(context.Context, *cfapi.ListRequest) (*cfapi.ListResponse, error) {
+	if f.ListErr != nil {
+		return nil, f.ListErr
+	}
+
+	return &cfapi.ListResponse{
+		Certificates: f.Certificates,
+		ResultInfo: cfapi.ResultInfo{
+			Page:       1,
+			PerPage:    len(f.Certificates),
+			Count:      len(f.Certificates),
+			TotalCount: len(f.Certificates),
+			TotalPages: 1,
+		},
+	}, nil
+}
+
+func (f *FakeClient) Get(_ context.Context, id string) (*cfapi.Certificate, error) {
+	for i := range f.Certificates {
+		if f.Certificates[i].Id == id {
+			return &f.Certificates[i], nil
+		}
+	}
+
+	return nil, &cfapi.Error{
+		StatusCode: 404,
+		Errors:     []cfapi.APIError{{Code: 1004, Message: "Failed to read certificate"}},
+		Class:      cfapi.ErrorClassValidation,
+	}
+}
+
+func (f *FakeClient) Revoke(_ context.Context, id string) (*cfapi.RevokeResponse, error) {
+	return &cfapi.RevokeResponse{
+		Id:        id,
+		RevokedAt: time.Now(),
+	}, nil
+}
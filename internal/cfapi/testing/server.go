@@ -0,0 +1,45 @@
+package testingcfapi
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi/fake"
+)
+
+// NewServer serves a fake Origin CA API for the duration of the test, and
+// returns it along with a client of the API.
+func NewServer(t testing.TB, options ...fake.Option) (*fake.Server, *cfapi.Client) {
+	t.Helper()
+
+	s, err := fake.New(options...)
+	if err != nil {
+		t.Fatalf("creating fake Origin CA: %s", err)
+	}
+
+	ts := httptest.NewTLSServer(s)
+	t.Cleanup(ts.Close)
+
+	endpoint, err := cfapi.WithEndpoint(ts.URL)
+	if err != nil {
+		t.Fatalf("configuring endpoint: %s", err)
+	}
+
+	return s, cfapi.New([]byte("v1.0-FFFF-FFFF"), cfapi.WithClient(ts.Client()), endpoint)
+}
+
+// Issue signs a request with a fake Origin CA API, as the Cloudflare API would.
+func Issue(t testing.TB, req *cfapi.SignRequest) *cfapi.SignResponse {
+	t.Helper()
+
+	_, c := NewServer(t)
+
+	resp, err := c.Sign(context.Background(), req)
+	if err != nil {
+		t.Fatalf("issuing certificate: %s", err)
+	}
+
+	return resp
+}
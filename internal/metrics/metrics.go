@@ -0,0 +1,118 @@
+// Package metrics defines the Prometheus metrics of the controller. They are
+// registered with the controller-runtime metrics registry, and served by the
+// manager alongside its own metrics.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "origin_ca_issuer"
+
+// Outcomes of signing a CertificateRequest, used as the outcome label of
+// Issuances.
+const (
+	OutcomeIssued = "issued"
+	OutcomeReused = "reused"
+	OutcomeDenied = "denied"
+	OutcomeFailed = "failed"
+	OutcomeError  = "error"
+)
+
+var (
+	// APIRequests counts requests to the Cloudflare API by endpoint, HTTP
+	// status and API error code.
+	APIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cfapi",
+		Name:      "requests_total",
+		Help:      "Requests to the Cloudflare API by endpoint, HTTP status and API error code.",
+	}, []string{"endpoint", "status", "code"})
+
+	// APIRequestDuration observes the latency of requests to the Cloudflare
+	// API, including retries.
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "cfapi",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the Cloudflare API, including retries.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "status"})
+
+	// Issuances counts signed CertificateRequests by issuer, request type and
+	// outcome.
+	Issuances = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "issuance_total",
+		Help:      "CertificateRequests signed by issuer, request type and outcome.",
+	}, []string{"kind", "namespace", "name", "request_type", "outcome"})
+
+	// ValidityAdjustment observes the difference between the validity period
+	// certificates were signed with and their requested duration.
+	ValidityAdjustment = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "validity_adjustment_days",
+		Help:      "Difference in days between the validity certificates were signed with and their requested duration.",
+		Buckets:   []float64{-365, -90, -30, -7, -1, 0, 1, 7, 30, 90, 365},
+	})
+
+	// IssuerReady is 1 for issuers that are Ready, and 0 otherwise.
+	IssuerReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "issuer_ready",
+		Help:      "Whether an issuer is Ready to sign certificates.",
+	}, []string{"kind", "namespace", "name"})
+
+	// SigningInFlight is the number of CertificateRequests being signed.
+	SigningInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "signing_in_flight",
+		Help:      "CertificateRequests currently being signed.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		APIRequests,
+		APIRequestDuration,
+		Issuances,
+		ValidityAdjustment,
+		IssuerReady,
+		SigningInFlight,
+	)
+}
+
+// ObserveAPIRequest records a request to the Cloudflare API. A zero status
+// means no response was received, and a zero code that the API reported no
+// error.
+func ObserveAPIRequest(endpoint string, status, code int, d time.Duration) {
+	statusLabel, codeLabel := "none", "none"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	if code != 0 {
+		codeLabel = strconv.Itoa(code)
+	}
+
+	APIRequests.WithLabelValues(endpoint, statusLabel, codeLabel).Inc()
+	APIRequestDuration.WithLabelValues(endpoint, statusLabel).Observe(d.Seconds())
+}
+
+// SetIssuerReady records the readiness of an issuer.
+func SetIssuerReady(kind, namespace, name string, ready bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+
+	IssuerReady.WithLabelValues(kind, namespace, name).Set(value)
+}
+
+// DeleteIssuer removes the metrics of a deleted issuer.
+func DeleteIssuer(kind, namespace, name string) {
+	IssuerReady.DeleteLabelValues(kind, namespace, name)
+}
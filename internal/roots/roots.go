@@ -0,0 +1,123 @@
+// Package roots provides the root certificates of the Cloudflare Origin CA,
+// which sign every Origin CA certificate.
+package roots
+
+import (
+	"crypto/x509"
+	_ "embed"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+)
+
+// The Cloudflare Origin CA RSA root, as published at
+// https://developers.cloudflare.com/ssl/origin-configuration/origin-ca/#cloudflare-origin-ca-root-certificate
+//
+//go:embed origin_ca_rsa_root.pem
+var rsaRoot []byte
+
+// Roots holds the PEM encoded roots for each request type. A nil root is
+// unknown.
+type Roots struct {
+	RSA []byte
+	ECC []byte
+}
+
+// Default returns the roots embedded in the controller. The ECC root is not
+// embedded, and must be provided with Load.
+func Default() *Roots {
+	return &Roots{RSA: rsaRoot}
+}
+
+// Load returns the default roots, overridden by the roots in the PEM bundle at
+// path, if any.
+func Load(path string) (*Roots, error) {
+	r := Default()
+	if path == "" {
+		return r, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	override, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid roots in %s: %w", path, err)
+	}
+
+	if override.RSA != nil {
+		r.RSA = override.RSA
+	}
+
+	if override.ECC != nil {
+		r.ECC = override.ECC
+	}
+
+	return r, nil
+}
+
+// Parse sorts the certificates in a PEM bundle into RSA and ECC roots by their
+// public key algorithm.
+func Parse(b []byte) (*Roots, error) {
+	r := &Roots{}
+
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if !cert.IsCA {
+			return nil, fmt.Errorf("certificate %q is not a CA", cert.Subject)
+		}
+
+		encoded := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+		switch cert.PublicKeyAlgorithm {
+		case x509.RSA:
+			r.RSA = append(r.RSA, encoded...)
+		case x509.ECDSA:
+			r.ECC = append(r.ECC, encoded...)
+		default:
+			return nil, fmt.Errorf("certificate %q has unsupported public key algorithm %s", cert.Subject, cert.PublicKeyAlgorithm)
+		}
+	}
+
+	if r.RSA == nil && r.ECC == nil {
+		return nil, errors.New("no certificates found")
+	}
+
+	return r, nil
+}
+
+// For returns the root signing certificates of the provided request type, or
+// nil if it is unknown.
+func (r *Roots) For(reqType v1.RequestType) []byte {
+	if r == nil {
+		return nil
+	}
+
+	switch reqType {
+	case v1.RequestTypeOriginRSA:
+		return r.RSA
+	case v1.RequestTypeOriginECC:
+		return r.ECC
+	}
+
+	return nil
+}
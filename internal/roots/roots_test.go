@@ -0,0 +1,119 @@
+package roots
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"gotest.tools/v3/assert"
+)
+
+func TestDefault(t *testing.T) {
+	block, rest := pem.Decode(Default().RSA)
+	assert.Assert(t, block != nil)
+	assert.Equal(t, len(bytes.TrimSpace(rest)), 0)
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NilError(t, err)
+	assert.Assert(t, cert.IsCA)
+	assert.DeepEqual(t, cert.Subject.OrganizationalUnit, []string{"CloudFlare Origin SSL Certificate Authority"})
+	assert.NilError(t, cert.CheckSignatureFrom(cert))
+
+	assert.Assert(t, Default().ECC == nil)
+}
+
+func TestParse(t *testing.T) {
+	rsaRoot := certificate(t, rsaKey(t), true)
+	eccRoot := certificate(t, ecKey(t), true)
+
+	tests := []struct {
+		name  string
+		pem   []byte
+		rsa   []byte
+		ecc   []byte
+		error string
+	}{
+		{name: "rsa", pem: rsaRoot, rsa: rsaRoot},
+		{name: "ecc", pem: eccRoot, ecc: eccRoot},
+		{name: "bundle", pem: append(append([]byte{}, eccRoot...), rsaRoot...), rsa: rsaRoot, ecc: eccRoot},
+		{name: "empty", pem: []byte("bogus"), error: "no certificates found"},
+		{name: "not a CA", pem: certificate(t, ecKey(t), false), error: `certificate "CN=Origin CA" is not a CA`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.pem)
+			if tt.error != "" {
+				assert.Error(t, err, tt.error)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, r.For(v1.RequestTypeOriginRSA), tt.rsa)
+			assert.DeepEqual(t, r.For(v1.RequestTypeOriginECC), tt.ecc)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	eccRoot := certificate(t, ecKey(t), true)
+
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NilError(t, os.WriteFile(path, eccRoot, 0o600))
+
+	r, err := Load(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, r.RSA, Default().RSA)
+	assert.DeepEqual(t, r.ECC, eccRoot)
+
+	r, err = Load("")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, r, Default())
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.crt"))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func rsaKey(t *testing.T) crypto.Signer {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	return k
+}
+
+func ecKey(t *testing.T) crypto.Signer {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	return k
+}
+
+func certificate(t *testing.T, key crypto.Signer, isCA bool) []byte {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Origin CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.NilError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
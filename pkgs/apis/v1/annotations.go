@@ -0,0 +1,32 @@
+package v1
+
+const (
+	// CertificateIDAnnotation is set on CertificateRequests to the Cloudflare
+	// ID of their certificate.
+	CertificateIDAnnotation = "cert-manager.k8s.cloudflare.com/certificate-id"
+
+	// IssuanceKeyAnnotation is set on CertificateRequests to the UID and CSR
+	// hash their certificate was signed for. A CertificateRequest whose key
+	// matches reuses the certificate recorded in CertificateIDAnnotation.
+	IssuanceKeyAnnotation = "cert-manager.k8s.cloudflare.com/issuance-key"
+
+	// ValidityAnnotation is set on CertificateRequests to the validity period,
+	// in days, their certificate was signed with by the Cloudflare API.
+	ValidityAnnotation = "cert-manager.k8s.cloudflare.com/validity-days"
+
+	// SignedRequestTypeAnnotation is set on CertificateRequests to the request
+	// type their certificate was signed with.
+	SignedRequestTypeAnnotation = "cert-manager.k8s.cloudflare.com/signed-request-type"
+
+	// ExpirationAnnotation is set on CertificateRequests to the time, in RFC
+	// 3339 format, their certificate expires.
+	ExpirationAnnotation = "cert-manager.k8s.cloudflare.com/expiration"
+
+	// RayIDAnnotation is set on CertificateRequests to the CF-Ray ID of the
+	// Cloudflare API request that signed their certificate.
+	RayIDAnnotation = "cert-manager.k8s.cloudflare.com/cf-ray"
+
+	// RequestTypeAnnotation may be set on CertificateRequests to override the
+	// request type of the issuer signing them. It must be a valid RequestType.
+	RequestTypeAnnotation = "cert-manager.k8s.cloudflare.com/request-type"
+)
@@ -1,7 +1,7 @@
 // +k8s:deepcopy-gen=package
 // +groupName=cert-manager.k8s.cloudflare.com
 
-// Package v1 is the v1 version of the OriginClusterIssuer API
+// Package v1 is the v1 version of the OriginIssuer and OriginClusterIssuer API
 package v1
 
 import (
@@ -23,5 +23,6 @@ var (
 )
 
 func init() {
+	SchemeBuilder.Register(&OriginIssuer{}, &OriginIssuerList{})
 	SchemeBuilder.Register(&OriginClusterIssuer{}, &OriginClusterIssuerList{})
 }
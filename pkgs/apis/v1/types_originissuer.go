@@ -2,14 +2,73 @@ package v1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+const (
+	// OriginIssuerKind is the kind of the namespaced OriginIssuer resource.
+	OriginIssuerKind = "OriginIssuer"
+
+	// OriginClusterIssuerKind is the kind of the cluster scoped OriginClusterIssuer resource.
+	OriginClusterIssuerKind = "OriginClusterIssuer"
+)
+
+// GenericIssuer is implemented by both OriginIssuer and OriginClusterIssuer,
+// which share their specification and status.
+// +k8s:deepcopy-gen=false
+type GenericIssuer interface {
+	runtime.Object
+	metav1.Object
+
+	GetSpec() *OriginClusterIssuerSpec
+	GetStatus() *OriginClusterIssuerStatus
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 
-// An OriginClusterIssuer represents the Cloudflare Origin CA as an external cert-manager issuer.
+// An OriginIssuer represents the Cloudflare Origin CA as an external cert-manager issuer.
 // It is scoped to a single namespace, so it can be used only by resources in the same
-// namespace.
+// namespace, and may only reference secrets in that namespace.
+type OriginIssuer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Desired state of the OriginIssuer resource
+	Spec OriginClusterIssuerSpec `json:"spec,omitempty"`
+
+	// Status of the OriginIssuer. This is set and managed automatically.
+	// +optional
+	Status OriginClusterIssuerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OriginIssuerList is a list of OriginIssuers.
+type OriginIssuerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata.omitempty"`
+
+	Items []OriginIssuer `json:"items"`
+}
+
+// GetSpec returns the OriginIssuer's spec.
+func (iss *OriginIssuer) GetSpec() *OriginClusterIssuerSpec {
+	return &iss.Spec
+}
+
+// GetStatus returns the OriginIssuer's status.
+func (iss *OriginIssuer) GetStatus() *OriginClusterIssuerStatus {
+	return &iss.Status
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+
+// An OriginClusterIssuer represents the Cloudflare Origin CA as an external cert-manager issuer.
+// The resource is a Cluster resource monitoring all certiicates inside the cluster, not tied
+// to a namespace.
 type OriginClusterIssuer struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -32,14 +91,42 @@ type OriginClusterIssuerList struct {
 	Items []OriginClusterIssuer `json:"items"`
 }
 
-// OriginClusterIssuerSpec is the specification of an OriginClusterIssuer. This includes any
-// configuration required for the issuer.
+// GetSpec returns the OriginClusterIssuer's spec.
+func (iss *OriginClusterIssuer) GetSpec() *OriginClusterIssuerSpec {
+	return &iss.Spec
+}
+
+// GetStatus returns the OriginClusterIssuer's status.
+func (iss *OriginClusterIssuer) GetStatus() *OriginClusterIssuerStatus {
+	return &iss.Status
+}
+
+// OriginClusterIssuerSpec is the specification of an OriginClusterIssuer or OriginIssuer.
+// This includes any configuration required for the issuer.
 type OriginClusterIssuerSpec struct {
 	// RequestType is the signature algorithm Cloudflare should use to sign the certificate.
+	// With Auto, the signature algorithm matching the public key of each
+	// CertificateRequest is used.
 	RequestType RequestType `json:"requestType"`
 
 	// Auth configures how to authenticate with the Cloudflare API.
 	Auth OriginClusterIssuerAuthentication `json:"auth"`
+
+	// Policy restricts which CertificateRequests the issuer will sign.
+	// If unset, every CertificateRequest referencing the issuer is signed.
+	// +optional
+	Policy *OriginIssuerPolicy `json:"policy,omitempty"`
+
+	// Validity configures how the duration requested by a CertificateRequest is
+	// mapped to one of the validity periods supported by the Cloudflare API.
+	// If unset, the nearest supported validity period is used.
+	// +optional
+	Validity *OriginIssuerValidity `json:"validity,omitempty"`
+
+	// AppendRoot appends the Cloudflare Origin CA root to the certificate
+	// chain of issued certificates, in addition to setting it as their CA.
+	// +optional
+	AppendRoot bool `json:"appendRoot,omitempty"`
 }
 
 // OriginClusterIssuerStatus contains status information about an OriginClusterIssuer
@@ -51,11 +138,21 @@ type OriginClusterIssuerStatus struct {
 }
 
 // OriginClusterIssuerAuthentication defines how to authenticate with the Cloudflare API.
-// Only one of `serviceKeyRef` may be specified.
+// Exactly one of `serviceKeyRef` or `apiTokenRef` must be specified.
 type OriginClusterIssuerAuthentication struct {
 	// ServiceKeyRef authenticates with an API Service Key.
 	// +optional
-	ServiceKeyRef SecretKeySelector `json:"serviceKeyRef,omitempty"`
+	ServiceKeyRef *SecretKeySelector `json:"serviceKeyRef,omitempty"`
+
+	// APITokenRef authenticates with an API Token, sent as a Bearer token.
+	// The token must be granted the Zone / SSL and Certificates / Edit permission.
+	// +optional
+	APITokenRef *SecretKeySelector `json:"apiTokenRef,omitempty"`
+
+	// ZoneID is the ID of a zone the credentials may manage certificates for.
+	// It is used to verify the credentials with the Cloudflare API.
+	// +optional
+	ZoneID string `json:"zoneID,omitempty"`
 }
 
 // SecretKeySelector contains a reference to a secret.
@@ -64,10 +161,90 @@ type SecretKeySelector struct {
 	Name string `json:"name"`
 	// Key of the secret to select from. Must be a valid secret key.
 	Key string `json:"key"`
-	// Namespace where secret is located.
-	Namespace string `json:"namespace"`
+	// Namespace of the secret. Required for an OriginClusterIssuer. An OriginIssuer
+	// may only reference secrets in its own namespace, which is used if unset.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// OriginIssuerPolicy restricts the hostnames an issuer will request certificates
+// for, and the namespaces it accepts CertificateRequests from. CertificateRequests
+// that violate the policy are failed without contacting the Cloudflare API.
+type OriginIssuerPolicy struct {
+	// AllowedDomains are the hostnames certificates may be requested for. A
+	// pattern starting with `*.` matches exactly one additional label, following
+	// DNS wildcard semantics: `*.example.com` matches `www.example.com` and
+	// `*.example.com`, but neither `example.com` nor `a.b.example.com`.
+	// If empty, any hostname is allowed.
+	// +optional
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+
+	// AllowedNamespaces are the namespaces CertificateRequests are accepted from.
+	// If empty, any namespace is allowed.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// DeniedNamespaces are namespaces CertificateRequests are never accepted from,
+	// even if they are otherwise allowed.
+	// +optional
+	DeniedNamespaces []string `json:"deniedNamespaces,omitempty"`
+
+	// NamespaceSelector restricts CertificateRequests to namespaces with matching
+	// labels. If unset, namespaces are not restricted by their labels.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// MaxSANs is the maximum number of hostnames a certificate may be requested for.
+	// If zero, the number of hostnames is not restricted.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxSANs int `json:"maxSANs,omitempty"`
+}
+
+// OriginIssuerValidity configures the validity of issued certificates. The Cloudflare
+// API only supports validity periods of 7, 30, 90, 365, 730, 1095 and 5475 days.
+type OriginIssuerValidity struct {
+	// DefaultDays is the validity used for CertificateRequests that do not
+	// request a duration. Defaults to 7 days.
+	// +kubebuilder:validation:Enum=7;30;90;365;730;1095;5475
+	// +optional
+	DefaultDays int `json:"defaultDays,omitempty"`
+
+	// MaxDays is the longest validity certificates are issued with. Longer
+	// requested durations are reduced to it, unless rounding is Strict.
+	// +kubebuilder:validation:Enum=7;30;90;365;730;1095;5475
+	// +optional
+	MaxDays int `json:"maxDays,omitempty"`
+
+	// Rounding is how a requested duration that is not a supported validity
+	// period is handled. Defaults to Nearest.
+	// +optional
+	Rounding ValidityRounding `json:"rounding,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=Nearest;Up;Down;Strict
+
+// ValidityRounding represents how requested durations are mapped to supported
+// validity periods.
+type ValidityRounding string
+
+const (
+	// ValidityRoundingNearest uses the nearest supported validity period.
+	ValidityRoundingNearest ValidityRounding = "Nearest"
+
+	// ValidityRoundingUp uses the shortest supported validity period that is
+	// at least the requested duration.
+	ValidityRoundingUp ValidityRounding = "Up"
+
+	// ValidityRoundingDown uses the longest supported validity period that is
+	// at most the requested duration.
+	ValidityRoundingDown ValidityRounding = "Down"
+
+	// ValidityRoundingStrict rejects CertificateRequests whose duration is not
+	// exactly a supported validity period.
+	ValidityRoundingStrict ValidityRounding = "Strict"
+)
+
 // OriginClusterIssuerCondition contains condition information for the OriginClusterIssuer.
 type OriginClusterIssuerCondition struct {
 	// Type of the condition, known values are ('Ready')
@@ -92,7 +269,7 @@ type OriginClusterIssuerCondition struct {
 	Message string `json:"message,omitempty"`
 }
 
-// +kubebuilder:validation:Enum=OriginRSA;OriginECC
+// +kubebuilder:validation:Enum=OriginRSA;OriginECC;Auto
 
 // RequestType represents the signature algorithm used to sign certificates.
 type RequestType string
@@ -103,9 +280,13 @@ const (
 
 	// RequestTypeOriginECC represents an ECDSA signature.
 	RequestTypeOriginECC RequestType = "OriginECC"
+
+	// RequestTypeAuto represents an RSA256 or ECDSA signature, depending on
+	// the public key of the CertificateRequest.
+	RequestTypeAuto RequestType = "Auto"
 )
 
-// +kubebuilder:validation:Enum=Ready
+// +kubebuilder:validation:Enum=Ready;CredentialsValid;APIReachable
 
 // ConditionType represents an OriginClusterIssuer condition value.
 type ConditionType string
@@ -116,6 +297,14 @@ const (
 	// If the `status` of this condition is `False`, CertificateRequest
 	// controllers should prevent attempts to sign certificates.
 	ConditionReady ConditionType = "Ready"
+
+	// ConditionCredentialsValid represents that the Cloudflare API accepted
+	// the credentials of an OriginClusterIssuer the last time they were verified.
+	ConditionCredentialsValid ConditionType = "CredentialsValid"
+
+	// ConditionAPIReachable represents that the Cloudflare API responded
+	// the last time the credentials of an OriginClusterIssuer were verified.
+	ConditionAPIReachable ConditionType = "APIReachable"
 )
 
 // +kubebuilder:validation:Enum=True;False;Unknown
@@ -5,7 +5,8 @@
 package v1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -13,7 +14,7 @@ func (in *OriginClusterIssuer) DeepCopyInto(out *OriginClusterIssuer) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -38,7 +39,16 @@ func (in *OriginClusterIssuer) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OriginClusterIssuerAuthentication) DeepCopyInto(out *OriginClusterIssuerAuthentication) {
 	*out = *in
-	out.ServiceKeyRef = in.ServiceKeyRef
+	if in.ServiceKeyRef != nil {
+		in, out := &in.ServiceKeyRef, &out.ServiceKeyRef
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
+	if in.APITokenRef != nil {
+		in, out := &in.APITokenRef, &out.APITokenRef
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginClusterIssuerAuthentication.
@@ -105,7 +115,17 @@ func (in *OriginClusterIssuerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OriginClusterIssuerSpec) DeepCopyInto(out *OriginClusterIssuerSpec) {
 	*out = *in
-	out.Auth = in.Auth
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(OriginIssuerPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Validity != nil {
+		in, out := &in.Validity, &out.Validity
+		*out = new(OriginIssuerValidity)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginClusterIssuerSpec.
@@ -140,6 +160,115 @@ func (in *OriginClusterIssuerStatus) DeepCopy() *OriginClusterIssuerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginIssuer) DeepCopyInto(out *OriginIssuer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginIssuer.
+func (in *OriginIssuer) DeepCopy() *OriginIssuer {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginIssuer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OriginIssuer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginIssuerList) DeepCopyInto(out *OriginIssuerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OriginIssuer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginIssuerList.
+func (in *OriginIssuerList) DeepCopy() *OriginIssuerList {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginIssuerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OriginIssuerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginIssuerPolicy) DeepCopyInto(out *OriginIssuerPolicy) {
+	*out = *in
+	if in.AllowedDomains != nil {
+		in, out := &in.AllowedDomains, &out.AllowedDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeniedNamespaces != nil {
+		in, out := &in.DeniedNamespaces, &out.DeniedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginIssuerPolicy.
+func (in *OriginIssuerPolicy) DeepCopy() *OriginIssuerPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginIssuerPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OriginIssuerValidity) DeepCopyInto(out *OriginIssuerValidity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OriginIssuerValidity.
+func (in *OriginIssuerValidity) DeepCopy() *OriginIssuerValidity {
+	if in == nil {
+		return nil
+	}
+	out := new(OriginIssuerValidity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeySelector) DeepCopyInto(out *SecretKeySelector) {
 	*out = *in
@@ -1,38 +1,57 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
 	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	"github.com/cloudflare/origin-ca-issuer/internal/metrics"
+	"github.com/cloudflare/origin-ca-issuer/internal/roots"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
 	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// provisionerWaitInterval is how often a CertificateRequest is retried while
+// the provisioner of its Ready issuer is being loaded.
+const provisionerWaitInterval = 5 * time.Second
+
 // CertificateRequestController implements a controller that reconciles CertificateRequests
 // that references this controller.
 type CertificateRequestController struct {
 	client.Client
 	Log        logr.Logger
+	Recorder   record.EventRecorder
 	Collection *provisioners.Collection
 
+	// Roots are the Origin CA roots set as the CA of issued certificates.
+	Roots *roots.Roots
+
 	Clock                  clock.Clock
 	CheckApprovedCondition bool
 }
 
-// +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 // Reconcile reconciles CertificateRequest by fetching a Cloudflare API provisioner from
-// the referenced OriginClusterIssuer, and providing the request's CSR.
+// the referenced OriginIssuer or OriginClusterIssuer, and providing the request's CSR.
 func (r *CertificateRequestController) Reconcile(ctx context.Context, cr *certmanager.CertificateRequest) (reconcile.Result, error) {
 	log := r.Log.WithValues("namespace", cr.Namespace, "certificaterequest", cr.Name)
 
@@ -69,6 +88,29 @@ func (r *CertificateRequestController) Reconcile(ctx context.Context, cr *certma
 		return reconcile.Result{}, nil
 	}
 
+	iss, issNamespaceName, ok := issuerFor(cr)
+	if !ok {
+		// An empty group may refer to cert-manager's own issuers, so only
+		// requests that explicitly name our group are misconfigured.
+		if cr.Spec.IssuerRef.Group == "" {
+			log.V(4).Info("resource does not specify an issuerRef kind that we are responsible for", "kind", cr.Spec.IssuerRef.Kind)
+
+			return reconcile.Result{}, nil
+		}
+
+		log.Info("CertificateRequest references an unsupported issuer kind. Marking as failed.", "kind", cr.Spec.IssuerRef.Kind)
+
+		if cr.Status.FailureTime == nil {
+			nowTime := metav1.NewTime(r.Clock.Now())
+			cr.Status.FailureTime = &nowTime
+		}
+
+		message := fmt.Sprintf("Referenced issuer kind %q is not supported, must be one of %s or %s", cr.Spec.IssuerRef.Kind, v1.OriginIssuerKind, v1.OriginClusterIssuerKind)
+		r.Recorder.Event(cr, core.EventTypeWarning, "InvalidIssuerRef", message)
+
+		return reconcile.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonFailed, message)
+	}
+
 	// If CertificateRequest has been denied, mark the CertificateRequest as
 	// Ready=Denied and set FailureTime if not already.
 	if cmutil.CertificateRequestIsDenied(cr) {
@@ -97,56 +139,205 @@ func (r *CertificateRequestController) Reconcile(ctx context.Context, cr *certma
 		return reconcile.Result{}, nil
 	}
 
-	if cr.Spec.IsCA {
-		log.Info("Origin Issuer does not support signing of CA certificates")
+	kind := iss.GetObjectKind().GroupVersionKind().Kind
 
-		return reconcile.Result{}, nil
-	}
+	if err := r.Client.Get(ctx, issNamespaceName, iss); err != nil {
+		log.Error(err, "failed to retrieve issuer resource", "kind", kind, "namespace", issNamespaceName.Namespace, "name", issNamespaceName.Name)
 
-	iss := v1.OriginClusterIssuer{}
-	issNamespaceName := types.NamespacedName{
-		// Doesn't restrict to any namespace, so search is cluster wide
-		Namespace: "",
-		Name:      cr.Spec.IssuerRef.Name,
-	}
+		if apierrors.IsNotFound(err) {
+			r.Recorder.Eventf(cr, core.EventTypeWarning, "IssuerNotFound", "Referenced %s %s does not exist", kind, issNamespaceName)
+		}
 
-	if err := r.Client.Get(ctx, issNamespaceName, &iss); err != nil {
-		log.Error(err, "failed to retrieve OriginClusterIssuer resource", "namespace", issNamespaceName.Namespace, "name", issNamespaceName.Name)
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("Failed to retrieve OriginClusterIssuer resource %s: %v", issNamespaceName, err))
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("Failed to retrieve %s resource %s: %v", kind, issNamespaceName, err))
 
 		return reconcile.Result{}, err
 	}
 
+	if err := checkPolicy(ctx, r.Client, iss.GetSpec().Policy, cr); err != nil {
+		var violation *PolicyViolation
+		if !errors.As(err, &violation) {
+			log.Error(err, "failed to evaluate issuer policy", "kind", kind, "namespace", issNamespaceName.Namespace, "name", issNamespaceName.Name)
+			_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("Failed to evaluate %s %s policy: %v", kind, issNamespaceName, err))
+
+			return reconcile.Result{}, err
+		}
+
+		log.Info("CertificateRequest violates issuer policy. Marking as failed.", "reason", violation.Reason)
+
+		if cr.Status.FailureTime == nil {
+			nowTime := metav1.NewTime(r.Clock.Now())
+			cr.Status.FailureTime = &nowTime
+		}
+
+		message := fmt.Sprintf("Denied by %s %s policy: %s", kind, issNamespaceName, violation.Reason)
+		r.Recorder.Event(cr, core.EventTypeWarning, "PolicyViolation", message)
+		recordIssuance(kind, issNamespaceName, iss.GetSpec().RequestType, metrics.OutcomeDenied)
+
+		return reconcile.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonFailed, message)
+	}
+
 	if !IssuerHasCondition(iss, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionTrue}) {
 		err := fmt.Errorf("resource %s is not ready", issNamespaceName)
-		log.Error(err, "issuer failed readiness checks", "namespace", issNamespaceName.Namespace, "name", issNamespaceName.Name)
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("OriginClusterIssuer %s is not Ready", issNamespaceName))
+		log.Error(err, "issuer failed readiness checks", "kind", kind, "namespace", issNamespaceName.Namespace, "name", issNamespaceName.Name)
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("%s %s is not Ready", kind, issNamespaceName))
+
+		// The request is enqueued again as soon as the issuer becomes Ready, by
+		// RequeuePendingRequests.
 
 		return reconcile.Result{}, err
 	}
 
-	p, ok := r.Collection.Load(issNamespaceName)
-	if !ok {
-		err := fmt.Errorf("provisioner %s not found", issNamespaceName)
-		log.Error(err, "failed to load provisioner for OriginClusterIssuer resource")
+	key := provisioners.KeyFor(issNamespaceName.Namespace, issNamespaceName.Name)
+	p, ok, err := r.Collection.LoadOrBuild(ctx, key)
+	if err != nil {
+		log.Error(err, "failed to load provisioner for issuer resource", "kind", kind, "namespace", issNamespaceName.Namespace, "name", issNamespaceName.Name)
 
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("Failed to load provisioner for OriginClusterIssuer resource %s", issNamespaceName))
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("Failed to load provisioner for %s %s: %v", kind, issNamespaceName, err))
 
 		return reconcile.Result{}, err
 	}
+	if !ok {
+		// The issuer is Ready, but its provisioner could not be built here and
+		// has not been loaded by the issuer controller yet.
+		log.Info("provisioner for issuer resource not loaded yet, waiting", "kind", kind, "namespace", issNamespaceName.Namespace, "name", issNamespaceName.Name)
+
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("Waiting for provisioner of %s %s to be loaded", kind, issNamespaceName))
+
+		return reconcile.Result{RequeueAfter: provisionerWaitInterval}, nil
+	}
+
+	metrics.SigningInFlight.Inc()
+	resp, err := p.Sign(ctx, cr)
+	metrics.SigningInFlight.Dec()
 
-	pem, err := p.Sign(ctx, cr)
 	if err != nil {
-		log.Error(err, "failed to sign certificate request")
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonFailed, fmt.Sprintf("Failed to sign certificate request: %v", err))
+		reqType, typeErr := p.RequestType(cr)
+		if typeErr != nil {
+			reqType = iss.GetSpec().RequestType
+		}
+
+		if provisioners.IsPermanent(err) {
+			log.Error(err, "failed to sign certificate request")
+			r.Recorder.Eventf(cr, core.EventTypeWarning, "SigningFailed", "Failed to sign certificate request: %v", err)
+			recordIssuance(kind, issNamespaceName, reqType, metrics.OutcomeFailed)
+
+			if cr.Status.FailureTime == nil {
+				nowTime := metav1.NewTime(r.Clock.Now())
+				cr.Status.FailureTime = &nowTime
+			}
+
+			return reconcile.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonFailed, fmt.Sprintf("Failed to sign certificate request: %v", err))
+		}
+
+		log.Error(err, "transient failure signing certificate request, will retry")
+		r.Recorder.Eventf(cr, core.EventTypeWarning, "SigningError", "Failed to sign certificate request, will retry: %v", err)
+		recordIssuance(kind, issNamespaceName, reqType, metrics.OutcomeError)
+
+		// The message deliberately omits the error itself, which carries a
+		// different CF-Ray ID on every attempt: a status change would
+		// immediately requeue the request and bypass the error backoff.
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, certmanager.CertificateRequestReasonPending, fmt.Sprintf("Failed to sign certificate request, will retry: %s error from Cloudflare API", cfapi.ClassOf(err)))
 
 		return reconcile.Result{}, err
 	}
 
-	cr.Status.Certificate = pem
-	_ = r.setStatus(ctx, cr, cmmeta.ConditionTrue, certmanager.CertificateRequestReasonIssued, "Certificate issued")
+	if resp.Reused {
+		r.Recorder.Eventf(cr, core.EventTypeNormal, "CertificateReused", "Reusing certificate %s already signed for this request", resp.ID)
+		recordIssuance(kind, issNamespaceName, resp.RequestType, metrics.OutcomeReused)
+	} else {
+		r.Recorder.Eventf(cr, core.EventTypeNormal, "Signed", "Certificate %s signed by %s %s (CF-Ray %s)", resp.ID, kind, issNamespaceName, resp.RayID)
+		recordIssuance(kind, issNamespaceName, resp.RequestType, metrics.OutcomeIssued)
+	}
+
+	// The certificate is recorded before the status update, so that it can be
+	// reused if the update fails.
+	r.annotate(ctx, log, cr, resp)
+	r.recordValidity(cr, resp.Validity)
+
+	cr.Status.Certificate = resp.Certificate
+
+	if root := r.Roots.For(resp.RequestType); root != nil {
+		cr.Status.CA = root
+
+		if iss.GetSpec().AppendRoot {
+			cr.Status.Certificate = bytes.Join([][]byte{resp.Certificate, root}, nil)
+		}
+	} else {
+		log.Info("no Origin CA root known for request type, not setting CA", "requestType", resp.RequestType)
+	}
+	// A failed update is retried, reusing the certificate that was just signed.
+	return reconcile.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionTrue, certmanager.CertificateRequestReasonIssued, "Certificate issued")
+}
+
+// annotate records the Cloudflare metadata of a signed certificate on its
+// CertificateRequest, so that it can be traced back to the Cloudflare dashboard.
+func (r *CertificateRequestController) annotate(ctx context.Context, log logr.Logger, cr *certmanager.CertificateRequest, resp *provisioners.SignResponse) {
+	patch := client.MergeFrom(cr.DeepCopy())
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, v1.CertificateIDAnnotation, resp.ID)
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, v1.IssuanceKeyAnnotation, provisioners.IssuanceKey(cr))
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, v1.ValidityAnnotation, strconv.Itoa(resp.Validity))
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, v1.SignedRequestTypeAnnotation, string(resp.RequestType))
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, v1.ExpirationAnnotation, resp.Expiration.UTC().Format(time.RFC3339))
+
+	if resp.RayID != "" {
+		metav1.SetMetaDataAnnotation(&cr.ObjectMeta, v1.RayIDAnnotation, resp.RayID)
+	}
+
+	if err := r.Client.Patch(ctx, cr, patch); err != nil {
+		log.Error(err, "failed to annotate CertificateRequest with certificate metadata")
+	}
+}
+
+// recordValidity records an event and metric comparing the validity period of
+// a signed certificate to the requested duration.
+func (r *CertificateRequestController) recordValidity(cr *certmanager.CertificateRequest, days int) {
+	if cr.Spec.Duration != nil {
+		metrics.ValidityAdjustment.Observe(float64(days) - cr.Spec.Duration.Hours()/24)
+	}
+
+	switch {
+	case cr.Spec.Duration == nil:
+		r.Recorder.Eventf(cr, core.EventTypeNormal, "Validity", "Certificate signed with the default validity of %d days", days)
+	case cr.Spec.Duration.Duration == time.Duration(days)*24*time.Hour:
+		r.Recorder.Eventf(cr, core.EventTypeNormal, "Validity", "Certificate signed with the requested validity of %d days", days)
+	default:
+		r.Recorder.Eventf(cr, core.EventTypeNormal, "ValidityAdjusted", "Certificate signed with a validity of %d days instead of the requested %s", days, cr.Spec.Duration.Duration)
+	}
+}
+
+// recordIssuance counts the outcome of signing a CertificateRequest with an issuer.
+func recordIssuance(kind string, name types.NamespacedName, reqType v1.RequestType, outcome string) {
+	metrics.Issuances.WithLabelValues(kind, name.Namespace, name.Name, string(reqType), outcome).Inc()
+}
+
+// issuerFor returns an empty issuer of the kind referenced by the CertificateRequest,
+// and the name to retrieve it with. OriginIssuers are looked up in the namespace of
+// the CertificateRequest. An empty kind defaults to OriginIssuer only when the
+// request names our group, mirroring cert-manager's own defaulting, as an empty
+// group and kind refer to a cert-manager Issuer. It returns false if the kind is
+// not one of ours.
+func issuerFor(cr *certmanager.CertificateRequest) (v1.GenericIssuer, types.NamespacedName, bool) {
+	name := cr.Spec.IssuerRef.Name
+
+	kind := cr.Spec.IssuerRef.Kind
+	if kind == "" && cr.Spec.IssuerRef.Group == v1.GroupVersion.Group {
+		kind = v1.OriginIssuerKind
+	}
+
+	switch kind {
+	case v1.OriginIssuerKind:
+		iss := &v1.OriginIssuer{}
+		iss.SetGroupVersionKind(v1.GroupVersion.WithKind(v1.OriginIssuerKind))
+
+		return iss, types.NamespacedName{Namespace: cr.Namespace, Name: name}, true
+	case v1.OriginClusterIssuerKind:
+		iss := &v1.OriginClusterIssuer{}
+		iss.SetGroupVersionKind(v1.GroupVersion.WithKind(v1.OriginClusterIssuerKind))
+
+		return iss, types.NamespacedName{Name: name}, true
+	}
 
-	return reconcile.Result{}, nil
+	return nil, types.NamespacedName{}, false
 }
 
 // setStatus is a helper function to set the CertifcateRequest status condition with reason and message, and update the API.
@@ -3,6 +3,9 @@ package controllers
 import (
 	"context"
 	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,15 +15,22 @@ import (
 	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
 	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
+	"github.com/cloudflare/origin-ca-issuer/internal/metrics"
+	"github.com/cloudflare/origin-ca-issuer/internal/roots"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
 	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	fakeClock "k8s.io/utils/clock/testing"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -39,12 +49,107 @@ func TestCertificateRequestReconcile(t *testing.T) {
 
 	cmutil.Clock = clock
 
+	csr, _, err := cmgen.CSR(x509.ECDSA, cmgen.SetCSRDNSNames("example.com"))
+	if err != nil {
+		t.Fatalf("creating CSR: %s", err)
+	}
+
+	server, api := fakeapi.NewServer(t)
+
+	signed, err := api.Sign(context.Background(), &cfapi.SignRequest{Hostnames: []string{"example.com"}, Validity: 7, Type: "origin-ecc", CSR: string(csr)})
+	if err != nil {
+		t.Fatalf("issuing certificate: %s", err)
+	}
+
+	signed.RayID = "0123456789abcdef-ABC"
+
+	// The fake Origin CA signs with an ECDSA root, standing in for the
+	// Origin CA ECC root.
+	root := server.RootPEM()
+	originRoots, err := roots.Parse(root)
+	if err != nil {
+		t.Fatalf("parsing root: %s", err)
+	}
+
+	request := func(kind string) *cmapi.CertificateRequest {
+		return cmgen.CertificateRequest("foobar",
+			cmgen.SetCertificateRequestNamespace("default"),
+			cmgen.SetCertificateRequestDuration(&metav1.Duration{Duration: 7 * 24 * time.Hour}),
+			cmgen.SetCertificateRequestCSR(csr),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "foobar",
+				Kind:  kind,
+				Group: "cert-manager.k8s.cloudflare.com",
+			}),
+		)
+	}
+
+	issuer := func() *v1.OriginClusterIssuer {
+		return &v1.OriginClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "foobar",
+			},
+			Spec: v1.OriginClusterIssuerSpec{
+				RequestType: v1.RequestTypeOriginECC,
+				Auth: v1.OriginClusterIssuerAuthentication{
+					ServiceKeyRef: &v1.SecretKeySelector{
+						Name:      "service-key-issuer",
+						Key:       "key",
+						Namespace: "default",
+					},
+				},
+			},
+			Status: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					{
+						Type:   v1.ConditionReady,
+						Status: v1.ConditionTrue,
+					},
+				},
+			},
+		}
+	}
+
+	namespacedIssuer := func() *v1.OriginIssuer {
+		iss := issuer()
+
+		return &v1.OriginIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foobar",
+				Namespace: "default",
+			},
+			Spec:   iss.Spec,
+			Status: iss.Status,
+		}
+	}
+
+	collection := func(key provisioners.Key, c *fakeapi.FakeClient) *provisioners.Collection {
+		p, err := provisioners.New(c, v1.RequestTypeOriginECC, logf.Log)
+		if err != nil {
+			t.Fatalf("error creating provisioner: %s", err)
+		}
+
+		return provisioners.CollectionWith([]provisioners.CollectionItem{
+			{
+				Key:         key,
+				Provisioner: p,
+			},
+		})
+	}
+
+	collectionWithError := func(err error) *provisioners.Collection {
+		return collection(provisioners.KeyFor("", "foobar"), &fakeapi.FakeClient{Err: err})
+	}
+
 	tests := []struct {
 		name          string
 		objects       []runtime.Object
 		collection    *provisioners.Collection
+		roots         *roots.Roots
+		outcome       string
 		expected      cmapi.CertificateRequestStatus
 		error         string
+		events        []string
 		namespaceName types.NamespacedName
 	}{
 		{
@@ -53,14 +158,7 @@ func TestCertificateRequestReconcile(t *testing.T) {
 				cmgen.CertificateRequest("foobar",
 					cmgen.SetCertificateRequestNamespace("default"),
 					cmgen.SetCertificateRequestDuration(&metav1.Duration{Duration: 7 * 24 * time.Hour}),
-					cmgen.SetCertificateRequestCSR((func() []byte {
-						csr, _, err := cmgen.CSR(x509.ECDSA)
-						if err != nil {
-							t.Fatalf("creating CSR: %s", err)
-						}
-
-						return csr
-					})()),
+					cmgen.SetCertificateRequestCSR(csr),
 					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
 						Name:  "foobar",
 						Kind:  "OriginClusterIssuer",
@@ -69,14 +167,14 @@ func TestCertificateRequestReconcile(t *testing.T) {
 				),
 				&v1.OriginClusterIssuer{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "foobar",
-						Namespace: "default",
+						Name: "foobar",
 					},
 					Spec: v1.OriginClusterIssuerSpec{
 						Auth: v1.OriginClusterIssuerAuthentication{
-							ServiceKeyRef: v1.SecretKeySelector{
-								Name: "service-key-issuer",
-								Key:  "key",
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name:      "service-key-issuer",
+								Key:       "key",
+								Namespace: "default",
 							},
 						},
 					},
@@ -92,23 +190,12 @@ func TestCertificateRequestReconcile(t *testing.T) {
 			},
 			collection: provisioners.CollectionWith([]provisioners.CollectionItem{
 				{
-					NamespacedName: types.NamespacedName{
-						Name:      "foobar",
-						Namespace: "default",
-					},
+					Key: provisioners.KeyFor("", "foobar"),
 					Provisioner: (func() *provisioners.Provisioner {
 						c := &fakeapi.FakeClient{
-							Response: &cfapi.SignResponse{
-								Id:          "1",
-								Certificate: "bogus",
-								Hostnames:   []string{"example.com"},
-								Expiration:  time.Time{},
-								Type:        "colemak",
-								Validity:    0,
-								CSR:         "foobar",
-							},
+							Response: signed,
 						}
-						p, err := provisioners.New(c, v1.RequestTypeOriginRSA, logf.Log)
+						p, err := provisioners.New(c, v1.RequestTypeOriginECC, logf.Log)
 						if err != nil {
 							t.Fatalf("error creating provisioner: %s", err)
 						}
@@ -117,6 +204,257 @@ func TestCertificateRequestReconcile(t *testing.T) {
 					}()),
 				},
 			}),
+			outcome: metrics.OutcomeIssued,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Issued",
+						Message:            "Certificate issued",
+					},
+				},
+				Certificate: []byte(signed.Certificate),
+			},
+			events: []string{
+				"Normal Signed Certificate " + signed.Id + " signed by OriginClusterIssuer /foobar (CF-Ray 0123456789abcdef-ABC)",
+				"Normal Validity Certificate signed with the requested validity of 7 days",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name:    "transient signing failure",
+			objects: []runtime.Object{request("OriginClusterIssuer"), issuer()},
+			collection: collectionWithError(&cfapi.Error{
+				StatusCode: 502,
+				RayID:      "0123456789abcdef-ABC",
+				Class:      cfapi.ErrorClassServer,
+			}),
+			outcome: metrics.OutcomeError,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Pending",
+						Message:            "Failed to sign certificate request, will retry: Server error from Cloudflare API",
+					},
+				},
+			},
+			error: "unable to sign request: Cloudflare API Error status=502 ray_id=0123456789abcdef-ABC",
+			events: []string{
+				"Warning SigningError Failed to sign certificate request, will retry: unable to sign request: Cloudflare API Error status=502 ray_id=0123456789abcdef-ABC",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name:    "permanent signing failure",
+			objects: []runtime.Object{request("OriginClusterIssuer"), issuer()},
+			collection: collectionWithError(&cfapi.Error{
+				StatusCode: 403,
+				Errors:     []cfapi.APIError{{Code: 10000, Message: "Authentication error"}},
+				RayID:      "0123456789abcdef-ABC",
+				Class:      cfapi.ErrorClassAuth,
+			}),
+			outcome: metrics.OutcomeFailed,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Failed",
+						Message:            "Failed to sign certificate request: unable to sign request: Cloudflare API Error code=10000 message=Authentication error ray_id=0123456789abcdef-ABC",
+					},
+				},
+				FailureTime: &now,
+			},
+			events: []string{
+				"Warning SigningFailed Failed to sign certificate request: unable to sign request: Cloudflare API Error code=10000 message=Authentication error ray_id=0123456789abcdef-ABC",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name: "CA certificate request",
+			objects: []runtime.Object{
+				cmgen.CertificateRequestFrom(request("OriginClusterIssuer"), cmgen.SetCertificateRequestIsCA(true)),
+				issuer(),
+			},
+			collection: collection(provisioners.KeyFor("", "foobar"), &fakeapi.FakeClient{
+				Response: signed,
+			}),
+			outcome: metrics.OutcomeFailed,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Failed",
+						Message:            "Failed to sign certificate request: invalid CSR: CA certificates are not supported",
+					},
+				},
+				FailureTime: &now,
+			},
+			events: []string{
+				"Warning SigningFailed Failed to sign certificate request: invalid CSR: CA certificates are not supported",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name: "reuse recorded certificate",
+			objects: []runtime.Object{
+				(func() *cmapi.CertificateRequest {
+					cr := request("OriginClusterIssuer")
+					cr.Annotations = map[string]string{
+						v1.CertificateIDAnnotation: signed.Id,
+						v1.IssuanceKeyAnnotation:   provisioners.IssuanceKey(cr),
+						v1.RayIDAnnotation:         "0123456789abcdef-ABC",
+					}
+
+					return cr
+				})(),
+				issuer(),
+			},
+			collection: collection(provisioners.KeyFor("", "foobar"), &fakeapi.FakeClient{
+				Err:          errors.New("certificate signed twice"),
+				Certificates: []cfapi.Certificate{*signed},
+			}),
+			outcome: metrics.OutcomeReused,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Issued",
+						Message:            "Certificate issued",
+					},
+				},
+				Certificate: []byte(signed.Certificate),
+			},
+			events: []string{
+				"Normal CertificateReused Reusing certificate " + signed.Id + " already signed for this request",
+				"Normal Validity Certificate signed with the requested validity of 7 days",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name:    "invalid certificate returned",
+			objects: []runtime.Object{request("OriginClusterIssuer"), issuer()},
+			collection: collection(provisioners.KeyFor("", "foobar"), &fakeapi.FakeClient{
+				Response: &cfapi.SignResponse{Certificate: "bogus"},
+			}),
+			outcome: metrics.OutcomeFailed,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Failed",
+						Message:            "Failed to sign certificate request: invalid certificate returned by the Cloudflare API: no PEM encoded certificate found",
+					},
+				},
+				FailureTime: &now,
+			},
+			events: []string{
+				"Warning SigningFailed Failed to sign certificate request: invalid certificate returned by the Cloudflare API: no PEM encoded certificate found",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name:    "working with OriginIssuer",
+			objects: []runtime.Object{request("OriginIssuer"), namespacedIssuer()},
+			collection: collection(provisioners.KeyFor("default", "foobar"), &fakeapi.FakeClient{
+				Response: signed,
+			}),
+			outcome: metrics.OutcomeIssued,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Issued",
+						Message:            "Certificate issued",
+					},
+				},
+				Certificate: []byte(signed.Certificate),
+			},
+			events: []string{
+				"Normal Signed Certificate " + signed.Id + " signed by OriginIssuer default/foobar (CF-Ray 0123456789abcdef-ABC)",
+				"Normal Validity Certificate signed with the requested validity of 7 days",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name:    "working with Origin CA root",
+			objects: []runtime.Object{request("OriginIssuer"), namespacedIssuer()},
+			collection: collection(provisioners.KeyFor("default", "foobar"), &fakeapi.FakeClient{
+				Response: signed,
+			}),
+			roots: originRoots,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Issued",
+						Message:            "Certificate issued",
+					},
+				},
+				Certificate: []byte(signed.Certificate),
+				CA:          root,
+			},
+			events: []string{
+				"Normal Signed Certificate " + signed.Id + " signed by OriginIssuer default/foobar (CF-Ray 0123456789abcdef-ABC)",
+				"Normal Validity Certificate signed with the requested validity of 7 days",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name: "working with Origin CA root appended",
+			objects: []runtime.Object{
+				request("OriginIssuer"),
+				(func() *v1.OriginIssuer {
+					iss := namespacedIssuer()
+					iss.Spec.AppendRoot = true
+
+					return iss
+				})(),
+			},
+			collection: collection(provisioners.KeyFor("default", "foobar"), &fakeapi.FakeClient{
+				Response: signed,
+			}),
+			roots: originRoots,
 			expected: cmapi.CertificateRequestStatus{
 				Conditions: []cmapi.CertificateRequestCondition{
 					{
@@ -127,13 +465,154 @@ func TestCertificateRequestReconcile(t *testing.T) {
 						Message:            "Certificate issued",
 					},
 				},
-				Certificate: []byte("bogus"),
+				Certificate: append([]byte(signed.Certificate), root...),
+				CA:          root,
+			},
+			events: []string{
+				"Normal Signed Certificate " + signed.Id + " signed by OriginIssuer default/foobar (CF-Ray 0123456789abcdef-ABC)",
+				"Normal Validity Certificate signed with the requested validity of 7 days",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name:       "provisioner not loaded yet",
+			objects:    []runtime.Object{request("OriginClusterIssuer"), issuer()},
+			collection: provisioners.CollectionWith(nil),
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Pending",
+						Message:            "Waiting for provisioner of OriginClusterIssuer /foobar to be loaded",
+					},
+				},
 			},
 			namespaceName: types.NamespacedName{
 				Namespace: "default",
 				Name:      "foobar",
 			},
 		},
+		{
+			name:    "empty kind defaults to OriginIssuer",
+			objects: []runtime.Object{request(""), issuer()},
+			collection: collection(provisioners.KeyFor("", "foobar"), &fakeapi.FakeClient{
+				Response: signed,
+			}),
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Pending",
+						Message:            `Failed to retrieve OriginIssuer resource default/foobar: originissuers.cert-manager.k8s.cloudflare.com "foobar" not found`,
+					},
+				},
+			},
+			error: `originissuers.cert-manager.k8s.cloudflare.com "foobar" not found`,
+			events: []string{
+				"Warning IssuerNotFound Referenced OriginIssuer default/foobar does not exist",
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name: "denied by issuer policy",
+			objects: []runtime.Object{
+				request("OriginClusterIssuer"),
+				(func() *v1.OriginClusterIssuer {
+					iss := issuer()
+					iss.Spec.Policy = &v1.OriginIssuerPolicy{DeniedNamespaces: []string{"default"}}
+
+					return iss
+				})(),
+			},
+			collection: collectionWithError(nil),
+			outcome:    metrics.OutcomeDenied,
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Failed",
+						Message:            `Denied by OriginClusterIssuer /foobar policy: namespace "default" is denied`,
+					},
+				},
+				FailureTime: &now,
+			},
+			events: []string{
+				`Warning PolicyViolation Denied by OriginClusterIssuer /foobar policy: namespace "default" is denied`,
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name:       "unsupported issuer kind",
+			objects:    []runtime.Object{request("OriginClusterIssuers"), issuer()},
+			collection: collectionWithError(nil),
+			expected: cmapi.CertificateRequestStatus{
+				Conditions: []cmapi.CertificateRequestCondition{
+					{
+						Type:               cmapi.CertificateRequestConditionReady,
+						Status:             cmmeta.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "Failed",
+						Message:            `Referenced issuer kind "OriginClusterIssuers" is not supported, must be one of OriginIssuer or OriginClusterIssuer`,
+					},
+				},
+				FailureTime: &now,
+			},
+			events: []string{
+				`Warning InvalidIssuerRef Referenced issuer kind "OriginClusterIssuers" is not supported, must be one of OriginIssuer or OriginClusterIssuer`,
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name: "issuer kind of another group",
+			objects: []runtime.Object{
+				cmgen.CertificateRequestFrom(request(""), cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+					Name: "foobar",
+					Kind: "ClusterIssuer",
+				})),
+				issuer(),
+			},
+			collection: collectionWithError(nil),
+			expected:   cmapi.CertificateRequestStatus{},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
+		{
+			name: "empty group and kind refer to a cert-manager Issuer",
+			objects: []runtime.Object{
+				cmgen.CertificateRequestFrom(request(""), cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+					Name: "foobar",
+				})),
+				namespacedIssuer(),
+			},
+			collection: collection(provisioners.KeyFor("default", "foobar"), &fakeapi.FakeClient{
+				Response: signed,
+			}),
+			expected: cmapi.CertificateRequestStatus{},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foobar",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -145,18 +624,38 @@ func TestCertificateRequestReconcile(t *testing.T) {
 				WithStatusSubresource(&cmapi.CertificateRequest{}).
 				Build()
 
+			recorder := record.NewFakeRecorder(len(tt.events))
+
 			controller := &CertificateRequestController{
 				Client:     client,
 				Log:        logf.Log,
+				Recorder:   recorder,
 				Collection: tt.collection,
+				Roots:      tt.roots,
+				Clock:      clock,
+			}
+
+			cr := &cmapi.CertificateRequest{}
+			if err := client.Get(context.TODO(), tt.namespaceName, cr); err != nil {
+				t.Fatalf("expected to retrieve certificate request from client: %s", err)
+			}
+
+			iss, issName, _ := issuerFor(cr)
+			issuances := func() float64 {
+				if iss == nil {
+					return 0
+				}
+
+				return testutil.ToFloat64(metrics.Issuances.WithLabelValues(issuerKind(iss), issName.Namespace, issName.Name, "OriginECC", tt.outcome))
 			}
+			before := issuances()
 
 			_, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), reconcile.Request{
 				NamespacedName: tt.namespaceName,
 			})
 
-			if err != nil {
-				if diff := cmp.Diff(err.Error(), tt.error); diff != "" {
+			if err != nil || tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
 					t.Fatalf("diff: (-wanted +got)\n%s", diff)
 				}
 			}
@@ -169,11 +668,142 @@ func TestCertificateRequestReconcile(t *testing.T) {
 				t.Fatalf("diff: (-want +got)\n%s", diff)
 			}
 
-			if tt.error == "" {
-				if _, ok := controller.Collection.Load(tt.namespaceName); !ok {
+			if tt.outcome != "" {
+				if diff := cmp.Diff(issuances()-before, 1.0); diff != "" {
+					t.Fatalf("diff: (-want +got)\n%s", diff)
+				}
+			}
+
+			close(recorder.Events)
+			var events []string
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			if diff := cmp.Diff(events, tt.events); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+
+			if len(tt.expected.Certificate) > 0 {
+				if diff := cmp.Diff(got.Annotations, map[string]string{
+					v1.CertificateIDAnnotation:     signed.Id,
+					v1.IssuanceKeyAnnotation:       provisioners.IssuanceKey(got),
+					v1.ValidityAnnotation:          "7",
+					v1.SignedRequestTypeAnnotation: "OriginECC",
+					v1.ExpirationAnnotation:        signed.Expiration.Format(time.RFC3339),
+					v1.RayIDAnnotation:             "0123456789abcdef-ABC",
+				}); diff != "" {
+					t.Fatalf("diff: (-want +got)\n%s", diff)
+				}
+
+				_, name, _ := issuerFor(got)
+				if _, ok := controller.Collection.Load(provisioners.KeyFor(name.Namespace, name.Name)); !ok {
 					t.Fatal("was unable to find provisioner")
 				}
 			}
 		})
 	}
 }
+
+func TestCertificateRequestReconcile_StatusUpdateFailed(t *testing.T) {
+	if err := cmapi.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	csr, _, err := cmgen.CSR(x509.ECDSA, cmgen.SetCSRDNSNames("example.com"))
+	if err != nil {
+		t.Fatalf("creating CSR: %s", err)
+	}
+
+	cr := cmgen.CertificateRequest("foobar",
+		cmgen.SetCertificateRequestNamespace("default"),
+		cmgen.SetCertificateRequestCSR(csr),
+		cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+			Name:  "foobar",
+			Kind:  v1.OriginClusterIssuerKind,
+			Group: v1.GroupVersion.Group,
+		}),
+	)
+	cr.UID = "4a3e6dca-3f2b-4d6b-a4e3-0d5a5b1b1c1d"
+
+	issuer := &v1.OriginClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "foobar"},
+		Spec: v1.OriginClusterIssuerSpec{
+			RequestType: v1.RequestTypeOriginECC,
+		},
+		Status: v1.OriginClusterIssuerStatus{
+			Conditions: []v1.OriginClusterIssuerCondition{{Type: v1.ConditionReady, Status: v1.ConditionTrue}},
+		},
+	}
+
+	failures := 1
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithRuntimeObjects(cr, issuer).
+		WithStatusSubresource(&cmapi.CertificateRequest{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourceUpdate: func(ctx context.Context, c ctrlclient.Client, subResourceName string, obj ctrlclient.Object, opts ...ctrlclient.SubResourceUpdateOption) error {
+				if failures > 0 {
+					failures--
+
+					return errors.New("status update failed")
+				}
+
+				return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	server, api := fakeapi.NewServer(t)
+
+	p, err := provisioners.New(api, v1.RequestTypeOriginECC, logf.Log)
+	if err != nil {
+		t.Fatalf("error creating provisioner: %s", err)
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	controller := &CertificateRequestController{
+		Client:   client,
+		Log:      logf.Log,
+		Recorder: recorder,
+		Collection: provisioners.CollectionWith([]provisioners.CollectionItem{
+			{Key: provisioners.KeyFor("", "foobar"), Provisioner: p},
+		}),
+		Clock: clock.RealClock{},
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "foobar"}}
+
+	_, err = reconcile.AsReconciler(client, controller).Reconcile(context.Background(), req)
+	if diff := cmp.Diff(fmt.Sprint(err), "status update failed"); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+
+	if _, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if signed, _ := server.Counts(); signed != 1 {
+		t.Fatalf("expected certificate to be signed once, got %d", signed)
+	}
+
+	got := &cmapi.CertificateRequest{}
+	if err := client.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatalf("expected to retrieve certificate request from client: %s", err)
+	}
+	if diff := cmp.Diff(string(got.Status.Certificate), server.Certificates()[0].Certificate); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+
+	close(recorder.Events)
+	var reasons []string
+	for e := range recorder.Events {
+		reasons = append(reasons, strings.Fields(e)[1])
+	}
+	if diff := cmp.Diff(reasons, []string{"Signed", "Validity", "CertificateReused", "Validity"}); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/cloudflare/origin-ca-issuer/internal/metrics"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// IssuerFinalizer is added to issuers when the controller is configured to
+// block their deletion until every CertificateRequest referencing them has
+// been completed.
+const IssuerFinalizer = "cert-manager.k8s.cloudflare.com/pending-requests"
+
+// finalizerRequeueInterval is how often the deletion of an issuer with pending
+// CertificateRequests is re-attempted.
+const finalizerRequeueInterval = 30 * time.Second
+
+// EvictDeleted returns an event handler that removes the provisioner of a
+// deleted issuer from the collection, along with its Cloudflare API client, and
+// its metrics. Issuers without a finalizer are gone by the time they would be reconciled,
+// so this is the only chance to clean up after them.
+func EvictDeleted(collection *provisioners.Collection) handler.EventHandler {
+	return handler.Funcs{
+		DeleteFunc: func(_ context.Context, e event.DeleteEvent, _ workqueue.RateLimitingInterface) {
+			collection.Forget(provisioners.KeyFor(e.Object.GetNamespace(), e.Object.GetName()))
+			metrics.DeleteIssuer(issuerKind(e.Object), e.Object.GetNamespace(), e.Object.GetName())
+		},
+	}
+}
+
+// finalize handles an issuer that is being deleted. If the issuer has our
+// finalizer, it is only removed once no CertificateRequests are pending against
+// the issuer, which continues to sign them until then.
+func (r *OriginClusterIssuerController) finalize(ctx context.Context, log logr.Logger, kind string, iss v1.GenericIssuer) (reconcile.Result, error) {
+	key := provisioners.KeyFor(iss.GetNamespace(), iss.GetName())
+
+	if !controllerutil.ContainsFinalizer(iss, IssuerFinalizer) {
+		r.Collection.Delete(key)
+
+		return reconcile.Result{}, nil
+	}
+
+	pending, err := r.pendingRequests(ctx, kind, iss)
+	if err != nil {
+		log.Error(err, "failed to list CertificateRequests")
+
+		return reconcile.Result{}, err
+	}
+
+	if pending > 0 {
+		log.Info("waiting for pending CertificateRequests before deleting "+kind, "pending", pending)
+
+		return reconcile.Result{RequeueAfter: finalizerRequeueInterval}, nil
+	}
+
+	r.Collection.Delete(key)
+
+	controllerutil.RemoveFinalizer(iss, IssuerFinalizer)
+
+	return reconcile.Result{}, r.Client.Update(ctx, iss)
+}
+
+// pendingRequests counts the CertificateRequests referencing the issuer that
+// have not been issued, failed or denied yet.
+func (r *OriginClusterIssuerController) pendingRequests(ctx context.Context, kind string, iss v1.GenericIssuer) (int, error) {
+	crs := certmanager.CertificateRequestList{}
+	if err := r.Client.List(ctx, &crs, client.InNamespace(iss.GetNamespace())); err != nil {
+		return 0, err
+	}
+
+	key := provisioners.KeyFor(iss.GetNamespace(), iss.GetName())
+
+	pending := 0
+	for i := range crs.Items {
+		cr := &crs.Items[i]
+
+		if cr.Spec.IssuerRef.Group != "" && cr.Spec.IssuerRef.Group != v1.GroupVersion.Group {
+			continue
+		}
+
+		_, name, ok := issuerFor(cr)
+		if !ok || provisioners.KeyFor(name.Namespace, name.Name) != key {
+			continue
+		}
+
+		if certificateRequestIsPending(cr) {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+// certificateRequestIsPending reports whether a CertificateRequest has yet to
+// reach a terminal state.
+func certificateRequestIsPending(cr *certmanager.CertificateRequest) bool {
+	if len(cr.Status.Certificate) > 0 || cmutil.CertificateRequestIsDenied(cr) {
+		return false
+	}
+
+	if cmutil.CertificateRequestHasCondition(cr, certmanager.CertificateRequestCondition{
+		Type:   certmanager.CertificateRequestConditionReady,
+		Status: cmmeta.ConditionTrue,
+	}) {
+		return false
+	}
+
+	for _, reason := range []string{certmanager.CertificateRequestReasonFailed, certmanager.CertificateRequestReasonDenied} {
+		if cmutil.CertificateRequestHasCondition(cr, certmanager.CertificateRequestCondition{
+			Type:   certmanager.CertificateRequestConditionReady,
+			Status: cmmeta.ConditionFalse,
+			Reason: reason,
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	fakeClock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestOriginClusterIssuerFinalizer(t *testing.T) {
+	if err := cmapi.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := fakeClock.NewFakeClock(time.Now().Truncate(time.Second))
+	deleted := metav1.NewTime(clock.Now())
+
+	issuer := func(deletionTimestamp *metav1.Time, finalizers ...string) *v1.OriginClusterIssuer {
+		return &v1.OriginClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "foo",
+				DeletionTimestamp: deletionTimestamp,
+				Finalizers:        finalizers,
+			},
+			Spec: v1.OriginClusterIssuerSpec{
+				RequestType: v1.RequestTypeOriginRSA,
+				Auth: v1.OriginClusterIssuerAuthentication{
+					ServiceKeyRef: &v1.SecretKeySelector{
+						Name:      "issuer-service-key",
+						Key:       "key",
+						Namespace: "default",
+					},
+				},
+			},
+		}
+	}
+
+	request := func(name string, opts ...cmgen.CertificateRequestModifier) *cmapi.CertificateRequest {
+		return cmgen.CertificateRequest(name, append([]cmgen.CertificateRequestModifier{
+			cmgen.SetCertificateRequestNamespace("default"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "foo",
+				Kind:  "OriginClusterIssuer",
+				Group: "cert-manager.k8s.cloudflare.com",
+			}),
+		}, opts...)...)
+	}
+
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		enableFinalizer bool
+		finalizers      []string
+		deleted         bool
+		requeue         bool
+		provisioner     bool
+	}{
+		{
+			name: "finalizer added",
+			objects: []runtime.Object{
+				issuer(nil),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer-service-key",
+						Namespace: "default",
+					},
+					Data: map[string][]byte{
+						"key": []byte("djEuMC0weDAwQkFCMTBD"),
+					},
+				},
+			},
+			enableFinalizer: true,
+			finalizers:      []string{IssuerFinalizer},
+			provisioner:     true,
+		},
+		{
+			name: "deletion blocked by pending request",
+			objects: []runtime.Object{
+				issuer(&deleted, IssuerFinalizer),
+				request("pending"),
+				request("issued", cmgen.SetCertificateRequestCertificate([]byte("bogus"))),
+			},
+			enableFinalizer: true,
+			finalizers:      []string{IssuerFinalizer},
+			requeue:         true,
+			provisioner:     true,
+		},
+		{
+			name: "deletion with completed requests",
+			objects: []runtime.Object{
+				issuer(&deleted, IssuerFinalizer),
+				request("issued", cmgen.SetCertificateRequestCertificate([]byte("bogus"))),
+				request("failed", cmgen.SetCertificateRequestStatusCondition(cmapi.CertificateRequestCondition{
+					Type:   cmapi.CertificateRequestConditionReady,
+					Status: cmmeta.ConditionFalse,
+					Reason: cmapi.CertificateRequestReasonFailed,
+				})),
+				request("other issuer", cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+					Name:  "foo",
+					Kind:  "OriginIssuer",
+					Group: "cert-manager.k8s.cloudflare.com",
+				})),
+			},
+			enableFinalizer: true,
+			deleted:         true,
+		},
+		{
+			name: "deletion with finalizer disabled",
+			objects: []runtime.Object{
+				issuer(&deleted, IssuerFinalizer),
+			},
+			deleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithRuntimeObjects(tt.objects...).
+				WithStatusSubresource(&v1.OriginClusterIssuer{}).
+				Build()
+
+			key := provisioners.KeyFor("", "foo")
+			p, err := provisioners.New(nil, v1.RequestTypeOriginRSA, logf.Log)
+			if err != nil {
+				t.Fatalf("error creating provisioner: %s", err)
+			}
+
+			controller := &OriginClusterIssuerController{
+				Recorder: record.NewFakeRecorder(10),
+				Client:   client,
+				Factory: cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+					return &fakeapi.FakeClient{}, nil
+				}),
+				Clock:           clock,
+				Log:             logf.Log,
+				Collection:      provisioners.CollectionWith([]provisioners.CollectionItem{{Key: key, Provisioner: p}}),
+				EnableFinalizer: tt.enableFinalizer,
+			}
+
+			result, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: "foo"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if requeue := result.RequeueAfter > 0; requeue != tt.requeue {
+				t.Fatalf("expected requeue to be %t, got %v", tt.requeue, result)
+			}
+
+			if _, ok := controller.Collection.Load(key); ok != tt.provisioner {
+				t.Fatalf("expected provisioner to be stored: %t", tt.provisioner)
+			}
+
+			got := &v1.OriginClusterIssuer{}
+			err = client.Get(context.TODO(), types.NamespacedName{Name: "foo"}, got)
+			if tt.deleted {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected issuer to be deleted, got %v", err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected to retrieve issuer from client: %s", err)
+			}
+
+			if diff := cmp.Diff(got.Finalizers, tt.finalizers); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
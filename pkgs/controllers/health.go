@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// cacheSyncTimeout is how long a readiness check waits for the informer caches
+// to sync.
+const cacheSyncTimeout = time.Second
+
+// CacheSynced returns a readiness check that passes once the informer caches
+// have synced.
+func CacheSynced(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), cacheSyncTimeout)
+		defer cancel()
+
+		if !c.WaitForCacheSync(ctx) {
+			return errors.New("informer caches have not synced")
+		}
+
+		return nil
+	}
+}
+
+// ProvisionersLoaded returns a readiness check that passes once the provisioner
+// of every Ready issuer has been loaded into the collection. Replicas only load
+// provisioners once elected leader, so the check passes until then. Issuers
+// that cannot be loaded, such as those with an invalid spec, are marked
+// NotReady by their reconcile, and so do not hold up readiness.
+func ProvisionersLoaded(c client.Reader, collection *provisioners.Collection, elected <-chan struct{}) healthz.Checker {
+	return func(req *http.Request) error {
+		select {
+		case <-elected:
+		default:
+			return nil
+		}
+
+		clusterIssuers := &v1.OriginClusterIssuerList{}
+		if err := c.List(req.Context(), clusterIssuers); err != nil {
+			return err
+		}
+
+		issuers := &v1.OriginIssuerList{}
+		if err := c.List(req.Context(), issuers); err != nil {
+			return err
+		}
+
+		var all []v1.GenericIssuer
+		for i := range clusterIssuers.Items {
+			all = append(all, &clusterIssuers.Items[i])
+		}
+		for i := range issuers.Items {
+			all = append(all, &issuers.Items[i])
+		}
+
+		for _, iss := range all {
+			if !iss.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+
+			if !IssuerHasCondition(iss, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionTrue}) {
+				continue
+			}
+
+			key := provisioners.KeyFor(iss.GetNamespace(), iss.GetName())
+			if _, ok := collection.Load(key); !ok {
+				return fmt.Errorf("provisioner %s has not been loaded", key)
+			}
+		}
+
+		return nil
+	}
+}
+
+// ReconcileWatchdog tracks running reconciles, so that a liveness check can
+// detect a reconcile loop wedged on a call that never returns.
+type ReconcileWatchdog struct {
+	Clock clock.Clock
+
+	// Timeout is how long a reconcile may run before the controller is
+	// considered wedged.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	next    uint64
+	running map[uint64]time.Time
+}
+
+// Wrap returns a reconciler that records the reconciles of r in the watchdog.
+func (w *ReconcileWatchdog) Wrap(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		defer w.track()()
+
+		return r.Reconcile(ctx, req)
+	})
+}
+
+// track records the start of a reconcile, and returns a function recording its
+// end.
+func (w *ReconcileWatchdog) track() func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running == nil {
+		w.running = make(map[uint64]time.Time)
+	}
+
+	id := w.next
+	w.next++
+	w.running[id] = w.Clock.Now()
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.running, id)
+	}
+}
+
+// Check is a liveness check that fails if a reconcile has been running for
+// longer than the timeout.
+func (w *ReconcileWatchdog) Check(_ *http.Request) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.Clock.Now()
+	for _, start := range w.running {
+		if d := now.Sub(start); d > w.Timeout {
+			return fmt.Errorf("reconcile has been running for %s, longer than %s", d, w.Timeout)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	fakeClock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestProvisionersLoaded(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(ready v1.ConditionStatus) v1.OriginClusterIssuerStatus {
+		return v1.OriginClusterIssuerStatus{
+			Conditions: []v1.OriginClusterIssuerCondition{{Type: v1.ConditionReady, Status: ready}},
+		}
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&v1.OriginClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Status:     status(v1.ConditionTrue),
+			},
+			&v1.OriginClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "unready"},
+				Status:     status(v1.ConditionFalse),
+			},
+			&v1.OriginIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+				Status:     status(v1.ConditionTrue),
+			},
+		).
+		Build()
+
+	p, err := provisioners.New(&fakeapi.FakeClient{}, v1.RequestTypeOriginECC, logf.Log)
+	if err != nil {
+		t.Fatalf("error creating provisioner: %s", err)
+	}
+
+	elected := make(chan struct{})
+	close(elected)
+
+	tests := []struct {
+		name    string
+		keys    []provisioners.Key
+		elected <-chan struct{}
+		error   string
+	}{
+		{
+			name:    "all loaded",
+			keys:    []provisioners.Key{provisioners.KeyFor("", "foo"), provisioners.KeyFor("default", "bar")},
+			elected: elected,
+		},
+		{
+			name:    "OriginClusterIssuer not loaded",
+			keys:    []provisioners.Key{provisioners.KeyFor("default", "bar")},
+			elected: elected,
+			error:   "provisioner OriginClusterIssuer/foo has not been loaded",
+		},
+		{
+			name:    "OriginIssuer not loaded",
+			keys:    []provisioners.Key{provisioners.KeyFor("", "foo")},
+			elected: elected,
+			error:   "provisioner OriginIssuer/default/bar has not been loaded",
+		},
+		{
+			name:    "not elected",
+			elected: make(chan struct{}),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var items []provisioners.CollectionItem
+			for _, key := range tt.keys {
+				items = append(items, provisioners.CollectionItem{Key: key, Provisioner: p})
+			}
+
+			check := ProvisionersLoaded(client, provisioners.CollectionWith(items), tt.elected)
+
+			err := check(httptest.NewRequest("GET", "/readyz", nil))
+			if err != nil || tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
+					t.Fatalf("diff: (-want +got)\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestProvisionersLoaded_InvalidSpec(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	// A Ready issuer edited to an invalid spec, as seen by a newly elected
+	// leader that has not loaded its provisioner.
+	issuer := &v1.OriginClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: v1.OriginClusterIssuerSpec{
+			RequestType: v1.RequestTypeOriginECC,
+			Validity:    &v1.OriginIssuerValidity{DefaultDays: 365, MaxDays: 90},
+		},
+		Status: v1.OriginClusterIssuerStatus{
+			Conditions: []v1.OriginClusterIssuerCondition{{Type: v1.ConditionReady, Status: v1.ConditionTrue}},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(issuer).
+		WithStatusSubresource(&v1.OriginClusterIssuer{}).
+		Build()
+
+	collection := provisioners.CollectionWith(nil)
+
+	elected := make(chan struct{})
+	close(elected)
+
+	check := ProvisionersLoaded(client, collection, elected)
+	req := httptest.NewRequest("GET", "/readyz", nil)
+
+	if diff := cmp.Diff(fmt.Sprint(check(req)), "provisioner OriginClusterIssuer/foo has not been loaded"); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+
+	controller := &OriginClusterIssuerController{
+		Client:     client,
+		Clock:      fakeClock.NewFakeClock(time.Now()),
+		Log:        logf.Log,
+		Recorder:   record.NewFakeRecorder(10),
+		Collection: collection,
+	}
+
+	if _, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "foo"},
+	}); err == nil {
+		t.Fatal("expected error for invalid spec")
+	}
+
+	if err := check(req); err != nil {
+		t.Fatalf("unexpected error after reconcile: %s", err)
+	}
+}
+
+func TestReconcileWatchdog(t *testing.T) {
+	clock := fakeClock.NewFakeClock(time.Now())
+
+	w := &ReconcileWatchdog{Clock: clock, Timeout: time.Minute}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	r := w.Wrap(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		close(started)
+		<-release
+
+		return reconcile.Result{}, nil
+	}))
+
+	go func() {
+		defer close(done)
+
+		_, _ = r.Reconcile(context.Background(), reconcile.Request{})
+	}()
+
+	<-started
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+
+	if err := w.Check(req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	clock.Step(2 * time.Minute)
+
+	if diff := cmp.Diff(fmt.Sprint(w.Check(req)), "reconcile has been running for 2m0s, longer than 1m0s"); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+
+	close(release)
+	<-done
+
+	if err := w.Check(req); err != nil {
+		t.Fatalf("unexpected error after reconcile returned: %s", err)
+	}
+}
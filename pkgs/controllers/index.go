@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// SecretRefField is the field index of OriginIssuers and OriginClusterIssuers
+// by the namespaced name of the Secret holding their credentials.
+const SecretRefField = ".spec.auth.secretRef"
+
+// IssuerRefField is the field index of CertificateRequests by the name of the
+// issuer they reference.
+const IssuerRefField = ".spec.issuerRef.name"
+
+// IndexSecretRef extracts the namespaced name of the auth Secret referenced by
+// an issuer, for use with SecretRefField.
+func IndexSecretRef(obj client.Object) []string {
+	iss, ok := obj.(v1.GenericIssuer)
+	if !ok {
+		return nil
+	}
+
+	spec := iss.GetSpec()
+
+	ref := spec.Auth.ServiceKeyRef
+	if spec.Auth.APITokenRef != nil {
+		ref = spec.Auth.APITokenRef
+	}
+
+	if ref == nil || ref.Name == "" {
+		return nil
+	}
+
+	name := types.NamespacedName{
+		Namespace: secretNamespace(iss, ref),
+		Name:      ref.Name,
+	}
+
+	return []string{name.String()}
+}
+
+// IssuersForSecret returns a handler.MapFunc that enqueues every issuer of the
+// kind of the provided list that references a Secret, so credentials are
+// reloaded when the Secret is created, rotated or deleted. The list must be
+// indexed by SecretRefField.
+func IssuersForSecret(c client.Reader, list client.ObjectList) handler.MapFunc {
+	return func(ctx context.Context, secret client.Object) []reconcile.Request {
+		issuers := list.DeepCopyObject().(client.ObjectList)
+		name := types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}
+
+		if err := c.List(ctx, issuers, client.MatchingFields{SecretRefField: name.String()}); err != nil {
+			return nil
+		}
+
+		var requests []reconcile.Request
+		_ = meta.EachListItem(issuers, func(o runtime.Object) error {
+			iss, ok := o.(client.Object)
+			if !ok {
+				return nil
+			}
+
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: iss.GetNamespace(), Name: iss.GetName()},
+			})
+
+			return nil
+		})
+
+		return requests
+	}
+}
+
+// IndexIssuerRef extracts the name of the issuer referenced by a
+// CertificateRequest, for use with IssuerRefField. Requests for issuers of
+// other groups or kinds are not indexed.
+func IndexIssuerRef(obj client.Object) []string {
+	cr, ok := obj.(*certmanager.CertificateRequest)
+	if !ok {
+		return nil
+	}
+
+	if cr.Spec.IssuerRef.Group != "" && cr.Spec.IssuerRef.Group != v1.GroupVersion.Group {
+		return nil
+	}
+
+	if _, _, ok := issuerFor(cr); !ok || cr.Spec.IssuerRef.Name == "" {
+		return nil
+	}
+
+	return []string{cr.Spec.IssuerRef.Name}
+}
+
+// RequeuePendingRequests returns an event handler that enqueues the pending
+// CertificateRequests of an issuer when it becomes Ready, so that they are
+// signed without waiting out the backoff of their failed reconciles.
+// CertificateRequests must be indexed by IssuerRefField.
+func RequeuePendingRequests(c client.Reader) handler.EventHandler {
+	ready := v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionTrue}
+
+	return handler.Funcs{
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			oldIss, ok := e.ObjectOld.(v1.GenericIssuer)
+			if !ok {
+				return
+			}
+			newIss, ok := e.ObjectNew.(v1.GenericIssuer)
+			if !ok {
+				return
+			}
+
+			if IssuerHasCondition(oldIss, ready) || !IssuerHasCondition(newIss, ready) {
+				return
+			}
+
+			for _, req := range pendingRequestsFor(ctx, c, newIss) {
+				q.Add(req)
+			}
+		},
+	}
+}
+
+// pendingRequestsFor returns the CertificateRequests referencing an issuer that
+// have yet to reach a terminal state.
+func pendingRequestsFor(ctx context.Context, c client.Reader, iss v1.GenericIssuer) []reconcile.Request {
+	crs := &certmanager.CertificateRequestList{}
+	if err := c.List(ctx, crs, client.InNamespace(iss.GetNamespace()), client.MatchingFields{IssuerRefField: iss.GetName()}); err != nil {
+		return nil
+	}
+
+	kind := issuerKind(iss)
+	issName := types.NamespacedName{Namespace: iss.GetNamespace(), Name: iss.GetName()}
+
+	var requests []reconcile.Request
+	for i := range crs.Items {
+		cr := &crs.Items[i]
+
+		ref, name, ok := issuerFor(cr)
+		if !ok || issuerKind(ref) != kind || name != issName {
+			continue
+		}
+
+		if !certificateRequestIsPending(cr) {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name},
+		})
+	}
+
+	return requests
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestIssuersForSecret(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := func(ref *v1.SecretKeySelector) v1.OriginClusterIssuerSpec {
+		return v1.OriginClusterIssuerSpec{
+			RequestType: v1.RequestTypeOriginECC,
+			Auth:        v1.OriginClusterIssuerAuthentication{ServiceKeyRef: ref},
+		}
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&v1.OriginClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec:       spec(&v1.SecretKeySelector{Name: "service-key", Key: "key", Namespace: "default"}),
+			},
+			&v1.OriginClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+				Spec:       spec(&v1.SecretKeySelector{Name: "service-key", Key: "key", Namespace: "other"}),
+			},
+			&v1.OriginIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "baz", Namespace: "default"},
+				Spec:       spec(&v1.SecretKeySelector{Name: "service-key", Key: "key"}),
+			},
+		).
+		WithIndex(&v1.OriginClusterIssuer{}, SecretRefField, IndexSecretRef).
+		WithIndex(&v1.OriginIssuer{}, SecretRefField, IndexSecretRef).
+		Build()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "service-key", Namespace: "default"},
+	}
+
+	tests := []struct {
+		name     string
+		mapFunc  func() []reconcile.Request
+		expected []reconcile.Request
+	}{
+		{
+			name: "OriginClusterIssuer",
+			mapFunc: func() []reconcile.Request {
+				return IssuersForSecret(client, &v1.OriginClusterIssuerList{})(context.Background(), secret)
+			},
+			expected: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "foo"}},
+			},
+		},
+		{
+			name: "OriginIssuer",
+			mapFunc: func() []reconcile.Request {
+				return IssuersForSecret(client, &v1.OriginIssuerList{})(context.Background(), secret)
+			},
+			expected: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "default", Name: "baz"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.mapFunc(), tt.expected); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequeuePendingRequests(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := certmanager.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(name string, ref cmmeta.ObjectReference, certificate []byte) *certmanager.CertificateRequest {
+		return &certmanager.CertificateRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       certmanager.CertificateRequestSpec{IssuerRef: ref},
+			Status:     certmanager.CertificateRequestStatus{Certificate: certificate},
+		}
+	}
+
+	clusterRef := cmmeta.ObjectReference{Name: "foo", Kind: v1.OriginClusterIssuerKind, Group: v1.GroupVersion.Group}
+	namespacedRef := cmmeta.ObjectReference{Name: "foo", Kind: v1.OriginIssuerKind, Group: v1.GroupVersion.Group}
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			request("cluster-pending", clusterRef, nil),
+			request("cluster-signed", clusterRef, []byte("certificate")),
+			request("namespaced-pending", namespacedRef, nil),
+			request("cert-manager-issuer", cmmeta.ObjectReference{Name: "foo"}, nil),
+			request("other-group", cmmeta.ObjectReference{Name: "foo", Kind: v1.OriginClusterIssuerKind, Group: "example.com"}, nil),
+		).
+		WithIndex(&certmanager.CertificateRequest{}, IssuerRefField, IndexIssuerRef).
+		Build()
+
+	status := func(ready v1.ConditionStatus) v1.OriginClusterIssuerStatus {
+		return v1.OriginClusterIssuerStatus{
+			Conditions: []v1.OriginClusterIssuerCondition{{Type: v1.ConditionReady, Status: ready}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		old      v1.GenericIssuer
+		new      v1.GenericIssuer
+		expected []reconcile.Request
+	}{
+		{
+			name: "OriginClusterIssuer became ready",
+			old:  &v1.OriginClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Status: status(v1.ConditionFalse)},
+			new:  &v1.OriginClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Status: status(v1.ConditionTrue)},
+			expected: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "default", Name: "cluster-pending"}},
+			},
+		},
+		{
+			name: "OriginIssuer became ready",
+			old:  &v1.OriginIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+			new:  &v1.OriginIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}, Status: status(v1.ConditionTrue)},
+			expected: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "default", Name: "namespaced-pending"}},
+			},
+		},
+		{
+			name: "OriginClusterIssuer already ready",
+			old:  &v1.OriginClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Status: status(v1.ConditionTrue)},
+			new:  &v1.OriginClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Status: status(v1.ConditionTrue)},
+		},
+		{
+			name: "OriginClusterIssuer became unready",
+			old:  &v1.OriginClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Status: status(v1.ConditionTrue)},
+			new:  &v1.OriginClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Status: status(v1.ConditionFalse)},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer q.ShutDown()
+
+			RequeuePendingRequests(client).Update(context.Background(), event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}, q)
+
+			var got []reconcile.Request
+			for q.Len() > 0 {
+				item, _ := q.Get()
+				got = append(got, item.(reconcile.Request))
+				q.Done(item)
+			}
+
+			if diff := cmp.Diff(got, tt.expected); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,17 +2,22 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	"github.com/cloudflare/origin-ca-issuer/internal/metrics"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
 	"github.com/go-logr/logr"
 	core "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -21,63 +26,107 @@ import (
 type OriginClusterIssuerController struct {
 	client.Client
 	Log        logr.Logger
+	Recorder   record.EventRecorder
 	Clock      clock.Clock
 	Factory    cfapi.Factory
 	Collection *provisioners.Collection
+
+	// VerifyInterval is how often the credentials of a Ready issuer are
+	// re-verified with the Cloudflare API. Zero disables re-verification.
+	VerifyInterval time.Duration
+
+	// EnableFinalizer adds IssuerFinalizer to issuers, blocking their deletion
+	// while CertificateRequests are pending against them.
+	EnableFinalizer bool
 }
 
 //go:generate controller-gen rbac:roleName=originclusterissuer-control paths=./. output:rbac:artifacts:config=../../deploy/rbac
 
-// +kubebuilder:rbac:groups=cert-manager.k8s.cloudflare.com,resources=originclusterissuers,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=cert-manager.k8s.cloudflare.com,resources=originclusterissuers,verbs=get;list;watch;create;update
 // +kubebuilder:rbac:groups=cert-manager.k8s.cloudflare.com,resources=originclusterissuers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete,namespace=origin-ca-issuer
 
 // Reconcile reconciles OriginClusterIssuer resources by managing Cloudflare API provisioners.
 func (r *OriginClusterIssuerController) Reconcile(ctx context.Context, iss *v1.OriginClusterIssuer) (reconcile.Result, error) {
-	log := r.Log.WithValues("namespace", iss.Namespace, "originclusterissuer", iss.Name)
+	log := r.Log.WithValues("originclusterissuer", iss.Name)
 
-	if err := validateOriginClusterIssuer(iss.Spec); err != nil {
-		log.Error(err, "failed to validate OriginClusterIssuer resource")
+	return r.reconcileIssuer(ctx, log, v1.OriginClusterIssuerKind, iss)
+}
 
-		return reconcile.Result{}, err
+// reconcileIssuer creates a Cloudflare API provisioner for either kind of issuer,
+// and stores it in the collection under the issuer's key.
+func (r *OriginClusterIssuerController) reconcileIssuer(ctx context.Context, log logr.Logger, kind string, iss v1.GenericIssuer) (reconcile.Result, error) {
+	if !iss.GetDeletionTimestamp().IsZero() {
+		return r.finalize(ctx, log, kind, iss)
 	}
 
-	secret := core.Secret{}
-	secretNamespaceName := types.NamespacedName{
-		Namespace: iss.Spec.Auth.ServiceKeyRef.Namespace,
-		Name:      iss.Spec.Auth.ServiceKeyRef.Name,
+	if r.EnableFinalizer && !controllerutil.ContainsFinalizer(iss, IssuerFinalizer) {
+		controllerutil.AddFinalizer(iss, IssuerFinalizer)
+
+		if err := r.Client.Update(ctx, iss); err != nil {
+			log.Error(err, "failed to add finalizer to "+kind+" resource")
+
+			return reconcile.Result{}, err
+		}
 	}
 
-	if err := r.Client.Get(ctx, secretNamespaceName, &secret); err != nil {
-		log.Error(err, "failed to retieve OriginClusterIssuer auth secret", "namespace", secretNamespaceName.Namespace, "name", secretNamespaceName.Name)
+	issKey := provisioners.KeyFor(iss.GetNamespace(), iss.GetName())
+
+	if err := validateIssuer(iss); err != nil {
+		log.Error(err, "failed to validate "+kind+" resource")
+		r.Recorder.Eventf(iss, core.EventTypeWarning, "InvalidSpec", "Invalid %s: %v", kind, err)
 
-		if apierrors.IsNotFound(err) {
+		// The issuer may have been edited from a valid spec, so stop signing
+		// with the provisioner built from it.
+		r.Collection.Delete(issKey)
+		_ = r.setStatus(ctx, iss, v1.ConditionFalse, "InvalidSpec", fmt.Sprintf("Invalid %s: %v", kind, err))
+
+		return reconcile.Result{}, err
+	}
+
+	creds, secretNamespaceName, err := r.credentials(ctx, iss)
+	if err != nil {
+		log.Error(err, "failed to retieve "+kind+" auth secret", "namespace", secretNamespaceName.Namespace, "name", secretNamespaceName.Name)
+
+		var missingKey *missingKeyError
+		if apierrors.IsNotFound(err) || errors.As(err, &missingKey) {
+			r.Recorder.Eventf(iss, core.EventTypeWarning, "SecretNotFound", "Failed to retrieve auth secret %s: %v", secretNamespaceName, err)
+
+			// The credentials were revoked by deleting the secret or its key, so stop
+			// signing with them.
+			r.Collection.Delete(issKey)
 			_ = r.setStatus(ctx, iss, v1.ConditionFalse, "NotFound", fmt.Sprintf("Failed to retrieve auth secret: %v", err))
 		} else {
+			r.Recorder.Eventf(iss, core.EventTypeWarning, "SecretError", "Failed to retrieve auth secret %s: %v", secretNamespaceName, err)
 			_ = r.setStatus(ctx, iss, v1.ConditionFalse, "Error", fmt.Sprintf("Failed to retrieve auth secret: %v", err))
 		}
 
 		return reconcile.Result{}, err
 	}
 
-	serviceKey, ok := secret.Data[iss.Spec.Auth.ServiceKeyRef.Key]
-	if !ok {
-		err := fmt.Errorf("secret %s does not contain key %q", secret.Name, iss.Spec.Auth.ServiceKeyRef.Key)
-		log.Error(err, "failed to retrieve OriginClusterIssuer auth secret")
-		_ = r.setStatus(ctx, iss, v1.ConditionFalse, "NotFound", fmt.Sprintf("Failed to retrieve auth secret: %v", err))
+	c, err := r.Factory.APIWith(creds)
+	if err != nil {
+		log.Error(err, "failed to create API client")
 
 		return reconcile.Result{}, err
 	}
 
-	c, err := r.Factory.APIWith(serviceKey)
-	if err != nil {
-		log.Error(err, "failed to create API client")
+	verified, verifyErr := r.verify(ctx, log, iss, c)
+	if !verified {
+		r.Collection.Delete(issKey)
 
-		return reconcile.Result{}, err
+		if verifyErr != nil {
+			_ = r.setStatus(ctx, iss, v1.ConditionFalse, "Unverified", "Failed to verify credentials with the Cloudflare API")
+
+			return reconcile.Result{}, verifyErr
+		}
+
+		return reconcile.Result{RequeueAfter: r.VerifyInterval}, r.setStatus(ctx, iss, v1.ConditionFalse, "InvalidCredentials", "Cloudflare API rejected the credentials")
 	}
 
-	p, err := provisioners.New(c, iss.Spec.RequestType, log)
+	p, err := newProvisioner(iss, c, log)
 	if err != nil {
 		log.Error(err, "failed to create provisioner")
 
@@ -86,32 +135,234 @@ func (r *OriginClusterIssuerController) Reconcile(ctx context.Context, iss *v1.O
 		return reconcile.Result{}, err
 	}
 
-	// TODO: GC these references once the OriginClusterIssuer has been removed.
-	r.Collection.Store(types.NamespacedName{Name: iss.Name}, p)
+	r.Collection.Store(issKey, p)
+
+	wasReady := IssuerHasCondition(iss, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionTrue})
+
+	if err := r.setStatus(ctx, iss, v1.ConditionTrue, "Verified", kind+" verified and ready to sign certificates"); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Only the transition to Ready is recorded, rather than every periodic
+	// re-verification.
+	if !wasReady {
+		r.Recorder.Event(iss, core.EventTypeNormal, "Verified", kind+" verified and ready to sign certificates")
+	}
 
-	return reconcile.Result{}, r.setStatus(ctx, iss, v1.ConditionTrue, "Verified", "OriginClusterIssuer verified and ready to sign certificates")
+	// A failed verification of previously verified credentials is retried
+	// with backoff, while the issuer remains usable.
+	if verifyErr != nil {
+		return reconcile.Result{}, verifyErr
+	}
+
+	return reconcile.Result{RequeueAfter: r.VerifyInterval}, nil
+}
+
+// LoadProvisioner builds the provisioner of a Ready issuer from its auth secret.
+// It is the loader of the collection, so that CertificateRequests reconciled
+// before their issuer, such as right after startup, are signed immediately. The
+// credentials are verified once the issuer itself is reconciled.
+func (r *OriginClusterIssuerController) LoadProvisioner(ctx context.Context, key provisioners.Key) (*provisioners.Provisioner, error) {
+	var iss v1.GenericIssuer = &v1.OriginClusterIssuer{}
+	if key.Kind == v1.OriginIssuerKind {
+		iss = &v1.OriginIssuer{}
+	}
+
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: key.Name}, iss); err != nil {
+		return nil, err
+	}
+
+	if !IssuerHasCondition(iss, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionTrue}) {
+		return nil, fmt.Errorf("%s is not ready", key)
+	}
+
+	if err := validateIssuer(iss); err != nil {
+		return nil, err
+	}
+
+	creds, _, err := r.credentials(ctx, iss)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := r.Factory.APIWith(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return newProvisioner(iss, c, r.Log.WithValues("namespace", key.Namespace, "issuer", key.Name))
+}
+
+// missingKeyError is returned when the auth secret of an issuer does not
+// contain the referenced key.
+type missingKeyError struct {
+	secret string
+	key    string
+}
+
+func (e *missingKeyError) Error() string {
+	return fmt.Sprintf("secret %s does not contain key %q", e.secret, e.key)
+}
+
+// credentials reads the Cloudflare API credentials of an issuer from its auth
+// secret, and returns them along with the name of the secret.
+func (r *OriginClusterIssuerController) credentials(ctx context.Context, iss v1.GenericIssuer) (cfapi.Credentials, types.NamespacedName, error) {
+	spec := iss.GetSpec()
+
+	ref := spec.Auth.ServiceKeyRef
+	if spec.Auth.APITokenRef != nil {
+		ref = spec.Auth.APITokenRef
+	}
+
+	name := types.NamespacedName{
+		Namespace: secretNamespace(iss, ref),
+		Name:      ref.Name,
+	}
+
+	secret := core.Secret{}
+	if err := r.Client.Get(ctx, name, &secret); err != nil {
+		return cfapi.Credentials{}, name, err
+	}
+
+	key, ok := secret.Data[ref.Key]
+	if !ok {
+		return cfapi.Credentials{}, name, &missingKeyError{secret: secret.Name, key: ref.Key}
+	}
+
+	if spec.Auth.APITokenRef != nil {
+		return cfapi.Credentials{APIToken: key}, name, nil
+	}
+
+	return cfapi.Credentials{ServiceKey: key}, name, nil
+}
+
+// newProvisioner creates a provisioner signing with the provided client, as
+// configured by the issuer's spec.
+func newProvisioner(iss v1.GenericIssuer, c cfapi.Interface, log logr.Logger) (*provisioners.Provisioner, error) {
+	spec := iss.GetSpec()
+
+	return provisioners.New(c, spec.RequestType, log, provisioners.WithValidity(spec.Validity))
+}
+
+// OriginIssuerController implements a controller that watches for changes
+// to namespaced OriginIssuer resources. It shares its configuration and
+// reconcile logic with the OriginClusterIssuerController.
+type OriginIssuerController struct {
+	OriginClusterIssuerController
+}
+
+// +kubebuilder:rbac:groups=cert-manager.k8s.cloudflare.com,resources=originissuers,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=cert-manager.k8s.cloudflare.com,resources=originissuers/status,verbs=get;update;patch
+
+// Reconcile reconciles OriginIssuer resources by managing Cloudflare API provisioners.
+func (r *OriginIssuerController) Reconcile(ctx context.Context, iss *v1.OriginIssuer) (reconcile.Result, error) {
+	log := r.Log.WithValues("namespace", iss.Namespace, "originissuer", iss.Name)
+
+	return r.reconcileIssuer(ctx, log, v1.OriginIssuerKind, iss)
 }
 
 // setStatus is a helper function to set the Issuer status condition with reason and message, and update the API.
-func (r *OriginClusterIssuerController) setStatus(ctx context.Context, iss *v1.OriginClusterIssuer, status v1.ConditionStatus, reason, message string) error {
+func (r *OriginClusterIssuerController) setStatus(ctx context.Context, iss v1.GenericIssuer, status v1.ConditionStatus, reason, message string) error {
 	SetIssuerCondition(iss, v1.ConditionReady, status, r.Log, r.Clock, reason, message)
+	metrics.SetIssuerReady(issuerKind(iss), iss.GetNamespace(), iss.GetName(), status == v1.ConditionTrue)
 
 	return r.Client.Status().Update(ctx, iss)
 }
 
+// issuerKind returns the kind of an issuer, which typed objects read from the
+// cache do not carry in their TypeMeta.
+func issuerKind(iss client.Object) string {
+	if _, ok := iss.(*v1.OriginIssuer); ok {
+		return v1.OriginIssuerKind
+	}
+
+	return v1.OriginClusterIssuerKind
+}
+
+// validateIssuer ensures the issuer's spec is valid, and that its auth secret
+// is referenced from a namespace the issuer may read from. OriginClusterIssuers
+// must name the namespace of their secret, while OriginIssuers may only use
+// secrets in their own namespace.
+func validateIssuer(iss v1.GenericIssuer) error {
+	spec := iss.GetSpec()
+	if err := validateOriginClusterIssuer(*spec); err != nil {
+		return err
+	}
+
+	field, ref := "serviceKeyRef", spec.Auth.ServiceKeyRef
+	if spec.Auth.APITokenRef != nil {
+		field, ref = "apiTokenRef", spec.Auth.APITokenRef
+	}
+
+	switch {
+	case iss.GetNamespace() == "" && ref.Namespace == "":
+		return fmt.Errorf("spec.auth.%s.namespace cannot be empty", field)
+	case iss.GetNamespace() != "" && ref.Namespace != "" && ref.Namespace != iss.GetNamespace():
+		return fmt.Errorf("spec.auth.%s.namespace must be empty or %q", field, iss.GetNamespace())
+	}
+
+	return nil
+}
+
+// secretNamespace returns the namespace of the referenced auth secret, which
+// defaults to the issuer's own namespace.
+func secretNamespace(iss v1.GenericIssuer, ref *v1.SecretKeySelector) string {
+	if ref.Namespace == "" {
+		return iss.GetNamespace()
+	}
+
+	return ref.Namespace
+}
+
 // validateOriginClusterIssuer ensures required fields are set, and enums are correctly set.
 // TODO: move this to another package?
 func validateOriginClusterIssuer(s v1.OriginClusterIssuerSpec) error {
 	switch {
-	case s.Auth.ServiceKeyRef.Name == "":
+	case s.Auth.ServiceKeyRef == nil && s.Auth.APITokenRef == nil:
+		return fmt.Errorf("spec.auth must specify one of serviceKeyRef or apiTokenRef")
+	case s.Auth.ServiceKeyRef != nil && s.Auth.APITokenRef != nil:
+		return fmt.Errorf("spec.auth must specify only one of serviceKeyRef or apiTokenRef")
+	case s.Auth.ServiceKeyRef != nil && s.Auth.ServiceKeyRef.Name == "":
 		return fmt.Errorf("spec.auth.serviceKeyRef.name cannot be empty")
-	case s.Auth.ServiceKeyRef.Key == "":
+	case s.Auth.ServiceKeyRef != nil && s.Auth.ServiceKeyRef.Key == "":
 		return fmt.Errorf("spec.auth.serviceKeyRef.key cannot be empty")
+	case s.Auth.APITokenRef != nil && s.Auth.APITokenRef.Name == "":
+		return fmt.Errorf("spec.auth.apiTokenRef.name cannot be empty")
+	case s.Auth.APITokenRef != nil && s.Auth.APITokenRef.Key == "":
+		return fmt.Errorf("spec.auth.apiTokenRef.key cannot be empty")
 	case s.RequestType == "":
 		return fmt.Errorf("spec.requestType cannot be empty")
-	case s.RequestType != v1.RequestTypeOriginRSA && s.RequestType != v1.RequestTypeOriginECC:
+	case s.RequestType != v1.RequestTypeOriginRSA && s.RequestType != v1.RequestTypeOriginECC && s.RequestType != v1.RequestTypeAuto:
 		return fmt.Errorf("spec.requestType has invalid value %q", s.RequestType)
 	}
 
-	return nil
+	if err := validatePolicy(s.Policy); err != nil {
+		return err
+	}
+
+	return validateValidity(s.Validity)
+}
+
+// validateValidity ensures an issuer's validity policy only uses validity periods
+// supported by the Cloudflare API.
+func validateValidity(v *v1.OriginIssuerValidity) error {
+	if v == nil {
+		return nil
+	}
+
+	switch {
+	case v.DefaultDays != 0 && !provisioners.IsAllowedValidity(v.DefaultDays):
+		return fmt.Errorf("spec.validity.defaultDays has invalid value %d", v.DefaultDays)
+	case v.MaxDays != 0 && !provisioners.IsAllowedValidity(v.MaxDays):
+		return fmt.Errorf("spec.validity.maxDays has invalid value %d", v.MaxDays)
+	case v.DefaultDays != 0 && v.MaxDays != 0 && v.DefaultDays > v.MaxDays:
+		return fmt.Errorf("spec.validity.defaultDays cannot exceed spec.validity.maxDays")
+	}
+
+	switch v.Rounding {
+	case "", v1.ValidityRoundingNearest, v1.ValidityRoundingUp, v1.ValidityRoundingDown, v1.ValidityRoundingStrict:
+		return nil
+	}
+
+	return fmt.Errorf("spec.validity.rounding has invalid value %q", v.Rounding)
 }
@@ -13,6 +13,7 @@ import (
 
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
 	"github.com/go-logr/zerologr"
@@ -22,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -55,15 +57,15 @@ func TestMain(m *testing.M) {
 func TestOriginClusterIssuerReconcileSuite(t *testing.T) {
 	issuer := &v1.OriginClusterIssuer{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "foo",
-			Namespace: "default",
+			Name: "foo",
 		},
 		Spec: v1.OriginClusterIssuerSpec{
 			RequestType: v1.RequestTypeOriginRSA,
 			Auth: v1.OriginClusterIssuerAuthentication{
-				ServiceKeyRef: v1.SecretKeySelector{
-					Name: "issuer-service-key",
-					Key:  "key",
+				ServiceKeyRef: &v1.SecretKeySelector{
+					Name:      "issuer-service-key",
+					Key:       "key",
+					Namespace: "default",
 				},
 			},
 		},
@@ -89,11 +91,12 @@ func TestOriginClusterIssuerReconcileSuite(t *testing.T) {
 	}
 	c := mgr.GetClient()
 
-	f := cfapi.FactoryFunc(func(serviceKey []byte) (cfapi.Interface, error) {
-		return nil, nil
+	f := cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+		return &fakeapi.FakeClient{}, nil
 	})
 
 	controller := &OriginClusterIssuerController{
+		Recorder:   record.NewFakeRecorder(10),
 		Client:     c,
 		Clock:      clock.RealClock{},
 		Factory:    f,
@@ -126,8 +129,7 @@ func TestOriginClusterIssuerReconcileSuite(t *testing.T) {
 	Eventually(t, func() bool {
 		iss := v1.OriginClusterIssuer{}
 		namespacedName := types.NamespacedName{
-			Namespace: issuer.Namespace,
-			Name:      issuer.Name,
+			Name: issuer.Name,
 		}
 
 		err := c.Get(context.TODO(), namespacedName, &iss)
@@ -135,13 +137,10 @@ func TestOriginClusterIssuerReconcileSuite(t *testing.T) {
 			return false
 		}
 
-		return IssuerHasCondition(iss, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionTrue})
+		return IssuerHasCondition(&iss, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionTrue})
 	}, 5*time.Second, 10*time.Millisecond, "OriginClusterIssuer reconciler")
 
-	_, ok := controller.Collection.Load(types.NamespacedName{
-		Namespace: issuer.Namespace,
-		Name:      issuer.Name,
-	})
+	_, ok := controller.Collection.Load(provisioners.KeyFor("", issuer.Name))
 
 	if !ok {
 		t.Fatal("was unable to find provisioner")
@@ -2,21 +2,30 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
+	"github.com/cloudflare/origin-ca-issuer/internal/metrics"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
 	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	fakeClock "k8s.io/utils/clock/testing"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -45,15 +54,15 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			objects: []runtime.Object{
 				&v1.OriginClusterIssuer{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "foo",
-						Namespace: "default",
+						Name: "foo",
 					},
 					Spec: v1.OriginClusterIssuerSpec{
 						RequestType: v1.RequestTypeOriginRSA,
 						Auth: v1.OriginClusterIssuerAuthentication{
-							ServiceKeyRef: v1.SecretKeySelector{
-								Name: "issuer-service-key",
-								Key:  "key",
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name:      "issuer-service-key",
+								Key:       "key",
+								Namespace: "default",
 							},
 						},
 					},
@@ -70,6 +79,20 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			},
 			expected: v1.OriginClusterIssuerStatus{
 				Conditions: []v1.OriginClusterIssuerCondition{
+					{
+						Type:               v1.ConditionAPIReachable,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Reachable",
+						Message:            "Cloudflare API responded",
+					},
+					{
+						Type:               v1.ConditionCredentialsValid,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Verified",
+						Message:            "Cloudflare API accepted the credentials",
+					},
 					{
 						Type:               v1.ConditionReady,
 						Status:             v1.ConditionTrue,
@@ -80,8 +103,7 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 				},
 			},
 			namespaceName: types.NamespacedName{
-				Namespace: "default",
-				Name:      "foo",
+				Name: "foo",
 			},
 		},
 		{
@@ -89,15 +111,15 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			objects: []runtime.Object{
 				&v1.OriginClusterIssuer{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "foo",
-						Namespace: "default",
+						Name: "foo",
 					},
 					Spec: v1.OriginClusterIssuerSpec{
 						RequestType: v1.RequestTypeOriginRSA,
 						Auth: v1.OriginClusterIssuerAuthentication{
-							ServiceKeyRef: v1.SecretKeySelector{
-								Name: "issuer-service-key",
-								Key:  "key",
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name:      "issuer-service-key",
+								Key:       "key",
+								Namespace: "default",
 							},
 						},
 					},
@@ -116,8 +138,7 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			},
 			error: `secrets "issuer-service-key" not found`,
 			namespaceName: types.NamespacedName{
-				Namespace: "default",
-				Name:      "foo",
+				Name: "foo",
 			},
 		},
 		{
@@ -125,15 +146,15 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			objects: []runtime.Object{
 				&v1.OriginClusterIssuer{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "foo",
-						Namespace: "default",
+						Name: "foo",
 					},
 					Spec: v1.OriginClusterIssuerSpec{
 						RequestType: v1.RequestTypeOriginRSA,
 						Auth: v1.OriginClusterIssuerAuthentication{
-							ServiceKeyRef: v1.SecretKeySelector{
-								Name: "issuer-service-key",
-								Key:  "key",
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name:      "issuer-service-key",
+								Key:       "key",
+								Namespace: "default",
 							},
 						},
 					},
@@ -159,8 +180,138 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			},
 			error: `secret issuer-service-key does not contain key "key"`,
 			namespaceName: types.NamespacedName{
-				Namespace: "default",
-				Name:      "foo",
+				Name: "foo",
+			},
+		},
+		{
+			name: "working with api token",
+			objects: []runtime.Object{
+				&v1.OriginClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+					},
+					Spec: v1.OriginClusterIssuerSpec{
+						RequestType: v1.RequestTypeOriginRSA,
+						Auth: v1.OriginClusterIssuerAuthentication{
+							APITokenRef: &v1.SecretKeySelector{
+								Name:      "issuer-api-token",
+								Key:       "token",
+								Namespace: "default",
+							},
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer-api-token",
+						Namespace: "default",
+					},
+					Data: map[string][]byte{
+						"token": []byte("Y2xvdWRmbGFyZQ=="),
+					},
+				},
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					{
+						Type:               v1.ConditionAPIReachable,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Reachable",
+						Message:            "Cloudflare API responded",
+					},
+					{
+						Type:               v1.ConditionCredentialsValid,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Verified",
+						Message:            "Cloudflare API accepted the credentials",
+					},
+					{
+						Type:               v1.ConditionReady,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Verified",
+						Message:            "OriginClusterIssuer verified and ready to sign certificates",
+					},
+				},
+			},
+			namespaceName: types.NamespacedName{
+				Name: "foo",
+			},
+		},
+		{
+			name: "multiple auth methods",
+			objects: []runtime.Object{
+				&v1.OriginClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+					},
+					Spec: v1.OriginClusterIssuerSpec{
+						RequestType: v1.RequestTypeOriginRSA,
+						Auth: v1.OriginClusterIssuerAuthentication{
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name:      "issuer-service-key",
+								Key:       "key",
+								Namespace: "default",
+							},
+							APITokenRef: &v1.SecretKeySelector{
+								Name:      "issuer-api-token",
+								Key:       "token",
+								Namespace: "default",
+							},
+						},
+					},
+				},
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					{
+						Type:               v1.ConditionReady,
+						Status:             v1.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "InvalidSpec",
+						Message:            "Invalid OriginClusterIssuer: spec.auth must specify only one of serviceKeyRef or apiTokenRef",
+					},
+				},
+			},
+			error: "spec.auth must specify only one of serviceKeyRef or apiTokenRef",
+			namespaceName: types.NamespacedName{
+				Name: "foo",
+			},
+		},
+		{
+			name: "secret without namespace",
+			objects: []runtime.Object{
+				&v1.OriginClusterIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+					},
+					Spec: v1.OriginClusterIssuerSpec{
+						RequestType: v1.RequestTypeOriginRSA,
+						Auth: v1.OriginClusterIssuerAuthentication{
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name: "issuer-service-key",
+								Key:  "key",
+							},
+						},
+					},
+				},
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					{
+						Type:               v1.ConditionReady,
+						Status:             v1.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "InvalidSpec",
+						Message:            "Invalid OriginClusterIssuer: spec.auth.serviceKeyRef.namespace cannot be empty",
+					},
+				},
+			},
+			error: "spec.auth.serviceKeyRef.namespace cannot be empty",
+			namespaceName: types.NamespacedName{
+				Name: "foo",
 			},
 		},
 	}
@@ -177,9 +328,10 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			collection := provisioners.CollectionWith(nil)
 
 			controller := &OriginClusterIssuerController{
-				Client: client,
-				Factory: cfapi.FactoryFunc(func(serviceKey []byte) (cfapi.Interface, error) {
-					return nil, nil
+				Recorder: record.NewFakeRecorder(10),
+				Client:   client,
+				Factory: cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+					return &fakeapi.FakeClient{}, nil
 				}),
 				Clock:      clock,
 				Log:        logf.Log,
@@ -205,10 +357,558 @@ func TestOriginClusterIssuerReconcile(t *testing.T) {
 			}
 
 			if tt.error == "" {
-				if _, ok := controller.Collection.Load(tt.namespaceName); !ok {
+				if _, ok := controller.Collection.Load(provisioners.KeyFor(tt.namespaceName.Namespace, tt.namespaceName.Name)); !ok {
 					t.Fatal("was unable to find provisioner")
 				}
 			}
 		})
 	}
 }
+
+func TestOriginIssuerReconcile(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := fakeClock.NewFakeClock(time.Now().Truncate(time.Second))
+	now := metav1.NewTime(clock.Now())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "issuer-service-key",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"key": []byte("djEuMC0weDAwQkFCMTBD"),
+		},
+	}
+
+	tests := []struct {
+		name          string
+		objects       []runtime.Object
+		expected      v1.OriginClusterIssuerStatus
+		error         string
+		namespaceName types.NamespacedName
+	}{
+		{
+			name: "working with secret in issuer namespace",
+			objects: []runtime.Object{
+				&v1.OriginIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "default",
+					},
+					Spec: v1.OriginClusterIssuerSpec{
+						RequestType: v1.RequestTypeOriginECC,
+						Auth: v1.OriginClusterIssuerAuthentication{
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name: "issuer-service-key",
+								Key:  "key",
+							},
+						},
+					},
+				},
+				secret,
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					{
+						Type:               v1.ConditionAPIReachable,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Reachable",
+						Message:            "Cloudflare API responded",
+					},
+					{
+						Type:               v1.ConditionCredentialsValid,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Verified",
+						Message:            "Cloudflare API accepted the credentials",
+					},
+					{
+						Type:               v1.ConditionReady,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: &now,
+						Reason:             "Verified",
+						Message:            "OriginIssuer verified and ready to sign certificates",
+					},
+				},
+			},
+			namespaceName: types.NamespacedName{
+				Namespace: "default",
+				Name:      "foo",
+			},
+		},
+		{
+			name: "secret in another namespace",
+			objects: []runtime.Object{
+				&v1.OriginIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "tenant",
+					},
+					Spec: v1.OriginClusterIssuerSpec{
+						RequestType: v1.RequestTypeOriginECC,
+						Auth: v1.OriginClusterIssuerAuthentication{
+							ServiceKeyRef: &v1.SecretKeySelector{
+								Name:      "issuer-service-key",
+								Key:       "key",
+								Namespace: "default",
+							},
+						},
+					},
+				},
+				secret,
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					{
+						Type:               v1.ConditionReady,
+						Status:             v1.ConditionFalse,
+						LastTransitionTime: &now,
+						Reason:             "InvalidSpec",
+						Message:            `Invalid OriginIssuer: spec.auth.serviceKeyRef.namespace must be empty or "tenant"`,
+					},
+				},
+			},
+			error: `spec.auth.serviceKeyRef.namespace must be empty or "tenant"`,
+			namespaceName: types.NamespacedName{
+				Namespace: "tenant",
+				Name:      "foo",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithRuntimeObjects(tt.objects...).
+				WithStatusSubresource(&v1.OriginIssuer{}).
+				Build()
+
+			controller := &OriginIssuerController{
+				OriginClusterIssuerController: OriginClusterIssuerController{
+					Recorder: record.NewFakeRecorder(10),
+					Client:   client,
+					Factory: cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+						return &fakeapi.FakeClient{}, nil
+					}),
+					Clock:      clock,
+					Log:        logf.Log,
+					Collection: provisioners.CollectionWith(nil),
+				},
+			}
+
+			_, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: tt.namespaceName,
+			})
+
+			if err != nil || tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
+					t.Fatalf("diff: (-wanted +got)\n%s", diff)
+				}
+			}
+
+			got := &v1.OriginIssuer{}
+			if err := client.Get(context.TODO(), tt.namespaceName, got); err != nil {
+				t.Fatalf("expected to retrieve issuer from client: %s", err)
+			}
+			if diff := cmp.Diff(got.Status, tt.expected); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+
+			_, ok := controller.Collection.Load(provisioners.KeyFor(tt.namespaceName.Namespace, tt.namespaceName.Name))
+			if ok != (tt.error == "") {
+				t.Fatalf("expected provisioner to be stored: %t", tt.error == "")
+			}
+		})
+	}
+}
+
+func TestOriginClusterIssuerReconcile_SecretDeleted(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := fakeClock.NewFakeClock(time.Now().Truncate(time.Second))
+
+	issuer := &v1.OriginClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+		},
+		Spec: v1.OriginClusterIssuerSpec{
+			RequestType: v1.RequestTypeOriginRSA,
+			Auth: v1.OriginClusterIssuerAuthentication{
+				ServiceKeyRef: &v1.SecretKeySelector{
+					Name:      "issuer-service-key",
+					Key:       "key",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "issuer-service-key",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"key": []byte("djEuMC0weDAwQkFCMTBD"),
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithRuntimeObjects(issuer, secret).
+		WithStatusSubresource(&v1.OriginClusterIssuer{}).
+		Build()
+
+	controller := &OriginClusterIssuerController{
+		Recorder: record.NewFakeRecorder(10),
+		Client:   client,
+		Factory: cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+			return &fakeapi.FakeClient{}, nil
+		}),
+		Clock:      clock,
+		Log:        logf.Log,
+		Collection: provisioners.CollectionWith(nil),
+	}
+
+	key := provisioners.KeyFor("", "foo")
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo"}}
+	ready := metrics.IssuerReady.WithLabelValues(v1.OriginClusterIssuerKind, "", "foo")
+
+	if _, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := controller.Collection.Load(key); !ok {
+		t.Fatal("was unable to find provisioner")
+	}
+	if got := testutil.ToFloat64(ready); got != 1 {
+		t.Fatalf("expected issuer ready metric to be 1, got %v", got)
+	}
+
+	if err := client.Delete(context.Background(), secret); err != nil {
+		t.Fatalf("deleting secret: %s", err)
+	}
+
+	if _, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected error after secret was deleted")
+	}
+	if _, ok := controller.Collection.Load(key); ok {
+		t.Fatal("expected provisioner to be evicted")
+	}
+
+	got := &v1.OriginClusterIssuer{}
+	if err := client.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatalf("expected to retrieve issuer from client: %s", err)
+	}
+	if !IssuerHasCondition(got, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionFalse}) {
+		t.Fatalf("expected issuer to not be ready, got %v", got.Status.Conditions)
+	}
+	if got := testutil.ToFloat64(ready); got != 0 {
+		t.Fatalf("expected issuer ready metric to be 0, got %v", got)
+	}
+}
+
+func TestOriginClusterIssuerController_LoadProvisioner(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := v1.OriginClusterIssuerSpec{
+		RequestType: v1.RequestTypeOriginRSA,
+		Auth: v1.OriginClusterIssuerAuthentication{
+			ServiceKeyRef: &v1.SecretKeySelector{
+				Name: "issuer-service-key",
+				Key:  "key",
+			},
+		},
+	}
+	ready := v1.OriginClusterIssuerStatus{
+		Conditions: []v1.OriginClusterIssuerCondition{{Type: v1.ConditionReady, Status: v1.ConditionTrue}},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithRuntimeObjects(
+			&v1.OriginIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Spec:       spec,
+				Status:     ready,
+			},
+			&v1.OriginIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "unready", Namespace: "default"},
+				Spec:       spec,
+			},
+			&v1.OriginIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "other"},
+				Spec:       spec,
+				Status:     ready,
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer-service-key", Namespace: "default"},
+				Data: map[string][]byte{
+					"key": []byte("djEuMC0weDAwQkFCMTBD"),
+				},
+			},
+		).
+		Build()
+
+	var got cfapi.Credentials
+	controller := &OriginClusterIssuerController{
+		Client: client,
+		Factory: cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+			got = creds
+
+			return &fakeapi.FakeClient{}, nil
+		}),
+		Log: logf.Log,
+	}
+
+	tests := []struct {
+		name  string
+		key   provisioners.Key
+		error string
+	}{
+		{
+			name: "ready issuer",
+			key:  provisioners.KeyFor("default", "foo"),
+		},
+		{
+			name:  "issuer not ready",
+			key:   provisioners.KeyFor("default", "unready"),
+			error: "OriginIssuer/default/unready is not ready",
+		},
+		{
+			name:  "issuer missing",
+			key:   provisioners.KeyFor("default", "missing"),
+			error: `originissuers.cert-manager.k8s.cloudflare.com "missing" not found`,
+		},
+		{
+			name:  "secret missing",
+			key:   provisioners.KeyFor("other", "foo"),
+			error: `secrets "issuer-service-key" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := controller.LoadProvisioner(context.Background(), tt.key)
+			if tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
+					t.Fatalf("diff: (-want +got)\n%s", diff)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if p == nil {
+				t.Fatal("expected provisioner to be built")
+			}
+			if diff := cmp.Diff(got, cfapi.Credentials{ServiceKey: []byte("djEuMC0weDAwQkFCMTBD")}); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOriginClusterIssuerReconcile_InvalidSpec(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &v1.OriginClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+		},
+		Spec: v1.OriginClusterIssuerSpec{
+			RequestType: v1.RequestTypeOriginRSA,
+			Auth: v1.OriginClusterIssuerAuthentication{
+				ServiceKeyRef: &v1.SecretKeySelector{
+					Name:      "issuer-service-key",
+					Key:       "key",
+					Namespace: "default",
+				},
+			},
+			Validity: &v1.OriginIssuerValidity{DefaultDays: 365, MaxDays: 90},
+		},
+		Status: v1.OriginClusterIssuerStatus{
+			Conditions: []v1.OriginClusterIssuerCondition{{Type: v1.ConditionReady, Status: v1.ConditionTrue}},
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithRuntimeObjects(issuer).
+		WithStatusSubresource(&v1.OriginClusterIssuer{}).
+		Build()
+
+	p, err := provisioners.New(&fakeapi.FakeClient{}, v1.RequestTypeOriginRSA, logf.Log)
+	if err != nil {
+		t.Fatalf("error creating provisioner: %s", err)
+	}
+
+	key := provisioners.KeyFor("", "foo")
+	controller := &OriginClusterIssuerController{
+		Recorder:   record.NewFakeRecorder(10),
+		Client:     client,
+		Clock:      fakeClock.NewFakeClock(time.Now()),
+		Log:        logf.Log,
+		Collection: provisioners.CollectionWith([]provisioners.CollectionItem{{Key: key, Provisioner: p}}),
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo"}}
+	if _, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected error for invalid spec")
+	}
+
+	if _, ok := controller.Collection.Load(key); ok {
+		t.Fatal("expected provisioner to be evicted")
+	}
+
+	got := &v1.OriginClusterIssuer{}
+	if err := client.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatalf("expected to retrieve issuer from client: %s", err)
+	}
+	if !IssuerHasCondition(got, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionFalse}) {
+		t.Fatalf("expected issuer to not be ready, got %v", got.Status.Conditions)
+	}
+}
+
+func TestOriginClusterIssuerReconcile_SecretForbidden(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &v1.OriginClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+		},
+		Spec: v1.OriginClusterIssuerSpec{
+			RequestType: v1.RequestTypeOriginRSA,
+			Auth: v1.OriginClusterIssuerAuthentication{
+				ServiceKeyRef: &v1.SecretKeySelector{
+					Name:      "issuer-service-key",
+					Key:       "key",
+					Namespace: "default",
+				},
+			},
+		},
+	}
+
+	forbidden := apierrors.NewForbidden(corev1.Resource("secrets"), "issuer-service-key", errors.New("access denied"))
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithRuntimeObjects(issuer).
+		WithStatusSubresource(&v1.OriginClusterIssuer{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, client ctrlclient.WithWatch, key ctrlclient.ObjectKey, obj ctrlclient.Object, opts ...ctrlclient.GetOption) error {
+				if _, ok := obj.(*corev1.Secret); ok {
+					return forbidden
+				}
+				return client.Get(ctx, key, obj, opts...)
+			},
+		}).
+		Build()
+
+	p, err := provisioners.New(&fakeapi.FakeClient{}, v1.RequestTypeOriginRSA, logf.Log)
+	if err != nil {
+		t.Fatalf("error creating provisioner: %s", err)
+	}
+
+	key := provisioners.KeyFor("", "foo")
+	recorder := record.NewFakeRecorder(10)
+	controller := &OriginClusterIssuerController{
+		Recorder:   recorder,
+		Client:     client,
+		Clock:      fakeClock.NewFakeClock(time.Now()),
+		Log:        logf.Log,
+		Collection: provisioners.CollectionWith([]provisioners.CollectionItem{{Key: key, Provisioner: p}}),
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo"}}
+	if _, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), req); !apierrors.IsForbidden(err) {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+
+	if _, ok := controller.Collection.Load(key); !ok {
+		t.Fatal("expected provisioner to be kept")
+	}
+
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	want := []string{
+		fmt.Sprintf("Warning SecretError Failed to retrieve auth secret default/issuer-service-key: %v", forbidden),
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Fatalf("diff: (-want +got)\n%s", diff)
+	}
+
+	got := &v1.OriginClusterIssuer{}
+	if err := client.Get(context.Background(), req.NamespacedName, got); err != nil {
+		t.Fatalf("expected to retrieve issuer from client: %s", err)
+	}
+	if !IssuerHasCondition(got, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: v1.ConditionFalse}) {
+		t.Fatalf("expected issuer to not be ready, got %v", got.Status.Conditions)
+	}
+}
+
+func TestValidateValidity(t *testing.T) {
+	tests := []struct {
+		name     string
+		validity *v1.OriginIssuerValidity
+		error    string
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:     "valid",
+			validity: &v1.OriginIssuerValidity{DefaultDays: 90, MaxDays: 365, Rounding: v1.ValidityRoundingUp},
+		},
+		{
+			name:     "invalid defaultDays",
+			validity: &v1.OriginIssuerValidity{DefaultDays: 60},
+			error:    "spec.validity.defaultDays has invalid value 60",
+		},
+		{
+			name:     "invalid maxDays",
+			validity: &v1.OriginIssuerValidity{MaxDays: 100},
+			error:    "spec.validity.maxDays has invalid value 100",
+		},
+		{
+			name:     "defaultDays greater than maxDays",
+			validity: &v1.OriginIssuerValidity{DefaultDays: 365, MaxDays: 90},
+			error:    "spec.validity.defaultDays cannot exceed spec.validity.maxDays",
+		},
+		{
+			name:     "invalid rounding",
+			validity: &v1.OriginIssuerValidity{Rounding: "Sideways"},
+			error:    `spec.validity.rounding has invalid value "Sideways"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateValidity(tt.validity)
+
+			if err != nil || tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
+					t.Fatalf("diff: (-wanted +got)\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/cert-manager/cert-manager/pkg/util/pki"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PolicyViolation is returned when a CertificateRequest is not allowed by the
+// policy of the issuer it references.
+type PolicyViolation struct {
+	Reason string
+}
+
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("denied by issuer policy: %s", v.Reason)
+}
+
+// checkPolicy returns a *PolicyViolation if the CertificateRequest is not
+// allowed by the issuer's policy. Other errors are returned if the policy could
+// not be evaluated.
+func checkPolicy(ctx context.Context, c client.Reader, policy *v1.OriginIssuerPolicy, cr *certmanager.CertificateRequest) error {
+	if policy == nil {
+		return nil
+	}
+
+	if err := checkNamespace(ctx, c, policy, cr.Namespace); err != nil {
+		return err
+	}
+
+	csr, err := pki.DecodeX509CertificateRequestBytes(cr.Spec.Request)
+	if err != nil {
+		// An undecodable CSR is rejected when signing.
+		return nil
+	}
+
+	hostnames := provisioners.Hostnames(csr)
+
+	if policy.MaxSANs > 0 && len(hostnames) > policy.MaxSANs {
+		return &PolicyViolation{Reason: fmt.Sprintf("%d hostnames requested, at most %d are allowed", len(hostnames), policy.MaxSANs)}
+	}
+
+	if len(policy.AllowedDomains) == 0 {
+		return nil
+	}
+
+	for _, hostname := range hostnames {
+		if !domainAllowed(policy.AllowedDomains, hostname) {
+			return &PolicyViolation{Reason: fmt.Sprintf("hostname %q is not allowed", hostname)}
+		}
+	}
+
+	return nil
+}
+
+func checkNamespace(ctx context.Context, c client.Reader, policy *v1.OriginIssuerPolicy, namespace string) error {
+	if slices.Contains(policy.DeniedNamespaces, namespace) {
+		return &PolicyViolation{Reason: fmt.Sprintf("namespace %q is denied", namespace)}
+	}
+
+	if len(policy.AllowedNamespaces) > 0 && !slices.Contains(policy.AllowedNamespaces, namespace) {
+		return &PolicyViolation{Reason: fmt.Sprintf("namespace %q is not allowed", namespace)}
+	}
+
+	if policy.NamespaceSelector == nil {
+		return nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(policy.NamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid namespace selector: %w", err)
+	}
+
+	ns := core.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		return fmt.Errorf("failed to retrieve namespace %s: %w", namespace, err)
+	}
+
+	if !selector.Matches(labels.Set(ns.Labels)) {
+		return &PolicyViolation{Reason: fmt.Sprintf("namespace %q does not match the namespace selector", namespace)}
+	}
+
+	return nil
+}
+
+// domainAllowed reports whether hostname matches any of the patterns. A
+// pattern starting with "*." matches exactly one additional label, which may
+// itself be a wildcard.
+func domainAllowed(patterns []string, hostname string) bool {
+	hostname = normalizeHostname(hostname)
+
+	for _, pattern := range patterns {
+		pattern = normalizeHostname(pattern)
+
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			label, ok := strings.CutSuffix(hostname, suffix)
+			if ok && label != "" && !strings.Contains(label, ".") {
+				return true
+			}
+
+			continue
+		}
+
+		if hostname == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validatePolicy ensures an issuer's policy can be evaluated.
+func validatePolicy(policy *v1.OriginIssuerPolicy) error {
+	if policy == nil {
+		return nil
+	}
+
+	for i, pattern := range policy.AllowedDomains {
+		if err := validateDomainPattern(pattern); err != nil {
+			return fmt.Errorf("spec.policy.allowedDomains[%d] is invalid: %w", i, err)
+		}
+	}
+
+	if policy.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(policy.NamespaceSelector); err != nil {
+			return fmt.Errorf("spec.policy.namespaceSelector is invalid: %w", err)
+		}
+	}
+
+	if policy.MaxSANs < 0 {
+		return fmt.Errorf("spec.policy.maxSANs cannot be negative")
+	}
+
+	return nil
+}
+
+// validateDomainPattern ensures a pattern only uses a wildcard as its leftmost label.
+func validateDomainPattern(pattern string) error {
+	rest := strings.TrimPrefix(pattern, "*.")
+
+	switch {
+	case rest == "":
+		return fmt.Errorf("pattern cannot be empty")
+	case strings.Contains(rest, "*"):
+		return fmt.Errorf("pattern %q may only contain a wildcard as its leftmost label", pattern)
+	}
+
+	return nil
+}
+
+func normalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(hostname), ".")
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"testing"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDomainAllowed(t *testing.T) {
+	patterns := []string{"example.com", "*.example.net"}
+
+	tests := []struct {
+		hostname string
+		allowed  bool
+	}{
+		{hostname: "example.com", allowed: true},
+		{hostname: "EXAMPLE.com.", allowed: true},
+		{hostname: "www.example.com", allowed: false},
+		{hostname: "www.example.net", allowed: true},
+		{hostname: "*.example.net", allowed: true},
+		{hostname: "example.net", allowed: false},
+		{hostname: "a.b.example.net", allowed: false},
+		{hostname: "wwwexample.net", allowed: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.hostname, func(t *testing.T) {
+			if got := domainAllowed(patterns, tt.hostname); got != tt.allowed {
+				t.Fatalf("expected %t, got %t", tt.allowed, got)
+			}
+		})
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	client := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "web"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		).
+		Build()
+
+	request := func(namespace string, hostnames ...string) *cmapi.CertificateRequest {
+		csr, _, err := cmgen.CSR(x509.ECDSA, cmgen.SetCSRDNSNames(hostnames...))
+		if err != nil {
+			t.Fatalf("creating CSR: %s", err)
+		}
+
+		return cmgen.CertificateRequest("foobar",
+			cmgen.SetCertificateRequestNamespace(namespace),
+			cmgen.SetCertificateRequestCSR(csr),
+		)
+	}
+
+	tests := []struct {
+		name    string
+		policy  *v1.OriginIssuerPolicy
+		request *cmapi.CertificateRequest
+		error   string
+	}{
+		{
+			name:    "no policy",
+			request: request("default", "example.com"),
+		},
+		{
+			name:    "allowed domain",
+			policy:  &v1.OriginIssuerPolicy{AllowedDomains: []string{"example.com", "*.example.com"}},
+			request: request("default", "example.com", "www.example.com"),
+		},
+		{
+			name:    "disallowed domain",
+			policy:  &v1.OriginIssuerPolicy{AllowedDomains: []string{"*.example.com"}},
+			request: request("default", "www.example.com", "example.org"),
+			error:   `denied by issuer policy: hostname "example.org" is not allowed`,
+		},
+		{
+			name:    "too many hostnames",
+			policy:  &v1.OriginIssuerPolicy{MaxSANs: 1},
+			request: request("default", "example.com", "www.example.com"),
+			error:   "denied by issuer policy: 2 hostnames requested, at most 1 are allowed",
+		},
+		{
+			name:    "allowed namespace",
+			policy:  &v1.OriginIssuerPolicy{AllowedNamespaces: []string{"default"}},
+			request: request("default", "example.com"),
+		},
+		{
+			name:    "namespace not allowed",
+			policy:  &v1.OriginIssuerPolicy{AllowedNamespaces: []string{"default"}},
+			request: request("other", "example.com"),
+			error:   `denied by issuer policy: namespace "other" is not allowed`,
+		},
+		{
+			name:    "denied namespace",
+			policy:  &v1.OriginIssuerPolicy{AllowedNamespaces: []string{"default"}, DeniedNamespaces: []string{"default"}},
+			request: request("default", "example.com"),
+			error:   `denied by issuer policy: namespace "default" is denied`,
+		},
+		{
+			name:    "matching namespace selector",
+			policy:  &v1.OriginIssuerPolicy{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}}},
+			request: request("default", "example.com"),
+		},
+		{
+			name:    "namespace selector mismatch",
+			policy:  &v1.OriginIssuerPolicy{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}}},
+			request: request("other", "example.com"),
+			error:   `denied by issuer policy: namespace "other" does not match the namespace selector`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPolicy(context.Background(), client, tt.policy, tt.request)
+
+			if err != nil || tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
+					t.Fatalf("diff: (-wanted +got)\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *v1.OriginIssuerPolicy
+		error  string
+	}{
+		{
+			name:   "valid",
+			policy: &v1.OriginIssuerPolicy{AllowedDomains: []string{"example.com", "*.example.com"}},
+		},
+		{
+			name:   "wildcard in the middle",
+			policy: &v1.OriginIssuerPolicy{AllowedDomains: []string{"www.*.example.com"}},
+			error:  `spec.policy.allowedDomains[0] is invalid: pattern "www.*.example.com" may only contain a wildcard as its leftmost label`,
+		},
+		{
+			name:   "bare wildcard",
+			policy: &v1.OriginIssuerPolicy{AllowedDomains: []string{"example.com", "*"}},
+			error:  `spec.policy.allowedDomains[1] is invalid: pattern "*" may only contain a wildcard as its leftmost label`,
+		},
+		{
+			name: "invalid selector",
+			policy: &v1.OriginIssuerPolicy{NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: "Near"},
+			}}},
+			error: `spec.policy.namespaceSelector is invalid: "Near" is not a valid label selector operator`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePolicy(tt.policy)
+
+			if err != nil || tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
+					t.Fatalf("diff: (-wanted +got)\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -7,12 +7,12 @@ import (
 	"k8s.io/utils/clock"
 )
 
-// IssuerHasCondition will return true if the given OriginClusterIssuer has a condition
-// matching the provided OriginClusterIssuerCondtion. Only the Type and Status fields
+// IssuerHasCondition will return true if the given OriginIssuer or OriginClusterIssuer has a
+// condition matching the provided OriginClusterIssuerCondtion. Only the Type and Status fields
 // are used in the comparison, meaning this function will return `true` even if
 // the Reason, Message, and LastTransitionTime fields do not match.
-func IssuerHasCondition(iss v1.OriginClusterIssuer, c v1.OriginClusterIssuerCondition) bool {
-	for _, cond := range iss.Status.Conditions {
+func IssuerHasCondition(iss v1.GenericIssuer, c v1.OriginClusterIssuerCondition) bool {
+	for _, cond := range iss.GetStatus().Conditions {
 		if c.Type == cond.Type && c.Status == cond.Status {
 			return true
 		}
@@ -21,7 +21,7 @@ func IssuerHasCondition(iss v1.OriginClusterIssuer, c v1.OriginClusterIssuerCond
 	return false
 }
 
-// SetIssuerCondition will set a condition on the given OriginClusterIssuer.
+// SetIssuerCondition will set a condition on the given OriginIssuer or OriginClusterIssuer.
 //
 // If no condition of the same type exists, the condition will be inserted with
 // the LastTransitionTime set to the current time.
@@ -32,7 +32,7 @@ func IssuerHasCondition(iss v1.OriginClusterIssuer, c v1.OriginClusterIssuerCond
 // If a condition of the same type and different state already exists, the
 // condition will be updated and the LastTransitionTime set to the current
 // time.
-func SetIssuerCondition(iss *v1.OriginClusterIssuer, conditionType v1.ConditionType, status v1.ConditionStatus, log logr.Logger, cl clock.Clock, reason, message string) {
+func SetIssuerCondition(iss v1.GenericIssuer, conditionType v1.ConditionType, status v1.ConditionStatus, log logr.Logger, cl clock.Clock, reason, message string) {
 	now := metav1.NewTime(cl.Now())
 	c := v1.OriginClusterIssuerCondition{
 		Type:               conditionType,
@@ -42,7 +42,8 @@ func SetIssuerCondition(iss *v1.OriginClusterIssuer, conditionType v1.ConditionT
 		LastTransitionTime: &now,
 	}
 
-	for i, condition := range iss.Status.Conditions {
+	st := iss.GetStatus()
+	for i, condition := range st.Conditions {
 		if condition.Type != conditionType {
 			continue
 		}
@@ -50,17 +51,17 @@ func SetIssuerCondition(iss *v1.OriginClusterIssuer, conditionType v1.ConditionT
 		if condition.Status == status {
 			c.LastTransitionTime = condition.LastTransitionTime
 		} else {
-			log.Info("found status change for OriginClusterIssuer; setting lastTransitionTime",
+			log.Info("found status change for issuer; setting lastTransitionTime",
 				"condition", condition.Type,
 				"old_status", condition.Status,
 				"new_status", c.Status,
 			)
 		}
 
-		iss.Status.Conditions[i] = c
+		st.Conditions[i] = c
 
 		return
 	}
 
-	iss.Status.Conditions = append(iss.Status.Conditions, c)
+	st.Conditions = append(st.Conditions, c)
 }
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+)
+
+// verify makes a cheap authenticated request to the Cloudflare API with the
+// issuer's credentials, and records whether the API could be reached and
+// accepted the credentials in the issuer's conditions. The issuer's status is
+// not updated.
+//
+// It returns false if the issuer must not be used to sign certificates, along
+// with an error if the verification should be retried. Credentials that were
+// verified before remain usable while they cannot be verified again.
+func (r *OriginClusterIssuerController) verify(ctx context.Context, log logr.Logger, iss v1.GenericIssuer, c cfapi.Interface) (bool, error) {
+	_, err := c.List(ctx, &cfapi.ListRequest{ZoneID: iss.GetSpec().Auth.ZoneID, PerPage: 1})
+
+	switch {
+	case err == nil:
+		SetIssuerCondition(iss, v1.ConditionAPIReachable, v1.ConditionTrue, log, r.Clock, "Reachable", "Cloudflare API responded")
+		SetIssuerCondition(iss, v1.ConditionCredentialsValid, v1.ConditionTrue, log, r.Clock, "Verified", "Cloudflare API accepted the credentials")
+
+		return true, nil
+	case cfapi.IsAuth(err):
+		log.Error(err, "Cloudflare API rejected credentials")
+		r.Recorder.Eventf(iss, core.EventTypeWarning, "InvalidCredentials", "Cloudflare API rejected the credentials: %v", err)
+
+		SetIssuerCondition(iss, v1.ConditionAPIReachable, v1.ConditionTrue, log, r.Clock, "Reachable", "Cloudflare API responded")
+		SetIssuerCondition(iss, v1.ConditionCredentialsValid, v1.ConditionFalse, log, r.Clock, "InvalidCredentials", fmt.Sprintf("Cloudflare API rejected the credentials: %s", apiErrorMessage(err)))
+
+		return false, nil
+	case cfapi.IsValidation(err):
+		// A rejected request does not tell whether the credentials are
+		// valid, such as when the API requires a zone to list certificates.
+		log.Error(err, "Cloudflare API rejected verification request")
+		r.Recorder.Eventf(iss, core.EventTypeWarning, "VerificationFailed", "Failed to verify credentials with the Cloudflare API: %v", err)
+
+		SetIssuerCondition(iss, v1.ConditionAPIReachable, v1.ConditionTrue, log, r.Clock, "Reachable", "Cloudflare API responded")
+	default:
+		log.Error(err, "failed to verify credentials with Cloudflare API")
+		r.Recorder.Eventf(iss, core.EventTypeWarning, "VerificationFailed", "Failed to verify credentials with the Cloudflare API: %v", err)
+
+		SetIssuerCondition(iss, v1.ConditionAPIReachable, v1.ConditionFalse, log, r.Clock, "Unreachable", fmt.Sprintf("Failed to reach Cloudflare API: %s", apiErrorMessage(err)))
+	}
+
+	verified := IssuerHasCondition(iss, v1.OriginClusterIssuerCondition{Type: v1.ConditionCredentialsValid, Status: v1.ConditionTrue})
+	if !verified {
+		SetIssuerCondition(iss, v1.ConditionCredentialsValid, v1.ConditionUnknown, log, r.Clock, "Unverified", "Credentials have not been verified with the Cloudflare API")
+	}
+
+	return verified, err
+}
+
+// apiErrorMessage describes a Cloudflare API error for use in a condition. It
+// leaves out the CF-Ray ID, which differs for every request, so that repeated
+// failures do not change the issuer's status.
+func apiErrorMessage(err error) string {
+	var e *cfapi.Error
+	if errors.As(err, &e) && len(e.Errors) > 0 {
+		msgs := make([]string, 0, len(e.Errors))
+		for _, err := range e.Errors {
+			msgs = append(msgs, fmt.Sprintf("code=%d message=%s", err.Code, err.Message))
+		}
+
+		return strings.Join(msgs, "; ")
+	}
+
+	return fmt.Sprintf("%s error", cfapi.ClassOf(err))
+}
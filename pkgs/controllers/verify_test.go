@@ -0,0 +1,351 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi/fake"
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/cloudflare/origin-ca-issuer/pkgs/provisioners"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	fakeClock "k8s.io/utils/clock/testing"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestOriginClusterIssuerVerify(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := fakeClock.NewFakeClock(time.Now().Truncate(time.Second))
+	now := metav1.NewTime(clock.Now())
+	earlier := metav1.NewTime(clock.Now().Add(-time.Hour))
+
+	authErr := &cfapi.Error{
+		StatusCode: 403,
+		Errors:     []cfapi.APIError{{Code: 10000, Message: "Authentication error"}},
+		RayID:      "0123456789abcdef-ABC",
+		Class:      cfapi.ErrorClassAuth,
+	}
+	serverErr := &cfapi.Error{
+		StatusCode: 502,
+		RayID:      "0123456789abcdef-ABC",
+		Class:      cfapi.ErrorClassServer,
+	}
+
+	validationErr := &cfapi.Error{
+		StatusCode: 400,
+		Errors:     []cfapi.APIError{{Code: 7003, Message: "Could not route to zone"}},
+		RayID:      "0123456789abcdef-ABC",
+		Class:      cfapi.ErrorClassValidation,
+	}
+
+	condition := func(t v1.ConditionType, status v1.ConditionStatus, at *metav1.Time, reason, message string) v1.OriginClusterIssuerCondition {
+		return v1.OriginClusterIssuerCondition{
+			Type:               t,
+			Status:             status,
+			LastTransitionTime: at,
+			Reason:             reason,
+			Message:            message,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		listErr     error
+		status      v1.OriginClusterIssuerStatus
+		expected    v1.OriginClusterIssuerStatus
+		result      reconcile.Result
+		events      []string
+		error       string
+		provisioner bool
+	}{
+		{
+			name: "verified",
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionAPIReachable, v1.ConditionTrue, &now, "Reachable", "Cloudflare API responded"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionTrue, &now, "Verified", "Cloudflare API accepted the credentials"),
+					condition(v1.ConditionReady, v1.ConditionTrue, &now, "Verified", "OriginClusterIssuer verified and ready to sign certificates"),
+				},
+			},
+			result:      reconcile.Result{RequeueAfter: time.Hour},
+			provisioner: true,
+			events: []string{
+				"Normal Verified OriginClusterIssuer verified and ready to sign certificates",
+			},
+		},
+		{
+			name:    "invalid credentials",
+			listErr: authErr,
+			status: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionReady, v1.ConditionTrue, &earlier, "Verified", "OriginClusterIssuer verified and ready to sign certificates"),
+				},
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionReady, v1.ConditionFalse, &now, "InvalidCredentials", "Cloudflare API rejected the credentials"),
+					condition(v1.ConditionAPIReachable, v1.ConditionTrue, &now, "Reachable", "Cloudflare API responded"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionFalse, &now, "InvalidCredentials", "Cloudflare API rejected the credentials: code=10000 message=Authentication error"),
+				},
+			},
+			result: reconcile.Result{RequeueAfter: time.Hour},
+			events: []string{
+				"Warning InvalidCredentials Cloudflare API rejected the credentials: Cloudflare API Error code=10000 message=Authentication error ray_id=0123456789abcdef-ABC",
+			},
+		},
+		{
+			name:    "unreachable before verification",
+			listErr: serverErr,
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionAPIReachable, v1.ConditionFalse, &now, "Unreachable", "Failed to reach Cloudflare API: Server error"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionUnknown, &now, "Unverified", "Credentials have not been verified with the Cloudflare API"),
+					condition(v1.ConditionReady, v1.ConditionFalse, &now, "Unverified", "Failed to verify credentials with the Cloudflare API"),
+				},
+			},
+			error: "Cloudflare API Error status=502 ray_id=0123456789abcdef-ABC",
+			events: []string{
+				"Warning VerificationFailed Failed to verify credentials with the Cloudflare API: Cloudflare API Error status=502 ray_id=0123456789abcdef-ABC",
+			},
+		},
+		{
+			name:    "unreachable after verification",
+			listErr: serverErr,
+			status: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionAPIReachable, v1.ConditionTrue, &earlier, "Reachable", "Cloudflare API responded"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionTrue, &earlier, "Verified", "Cloudflare API accepted the credentials"),
+					condition(v1.ConditionReady, v1.ConditionTrue, &earlier, "Verified", "OriginClusterIssuer verified and ready to sign certificates"),
+				},
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionAPIReachable, v1.ConditionFalse, &now, "Unreachable", "Failed to reach Cloudflare API: Server error"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionTrue, &earlier, "Verified", "Cloudflare API accepted the credentials"),
+					condition(v1.ConditionReady, v1.ConditionTrue, &earlier, "Verified", "OriginClusterIssuer verified and ready to sign certificates"),
+				},
+			},
+			error:       "Cloudflare API Error status=502 ray_id=0123456789abcdef-ABC",
+			provisioner: true,
+			events: []string{
+				"Warning VerificationFailed Failed to verify credentials with the Cloudflare API: Cloudflare API Error status=502 ray_id=0123456789abcdef-ABC",
+			},
+		},
+		{
+			name:    "rejected request before verification",
+			listErr: validationErr,
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionAPIReachable, v1.ConditionTrue, &now, "Reachable", "Cloudflare API responded"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionUnknown, &now, "Unverified", "Credentials have not been verified with the Cloudflare API"),
+					condition(v1.ConditionReady, v1.ConditionFalse, &now, "Unverified", "Failed to verify credentials with the Cloudflare API"),
+				},
+			},
+			error: "Cloudflare API Error code=7003 message=Could not route to zone ray_id=0123456789abcdef-ABC",
+			events: []string{
+				"Warning VerificationFailed Failed to verify credentials with the Cloudflare API: Cloudflare API Error code=7003 message=Could not route to zone ray_id=0123456789abcdef-ABC",
+			},
+		},
+		{
+			name:    "rejected request after verification",
+			listErr: validationErr,
+			status: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionAPIReachable, v1.ConditionTrue, &earlier, "Reachable", "Cloudflare API responded"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionTrue, &earlier, "Verified", "Cloudflare API accepted the credentials"),
+					condition(v1.ConditionReady, v1.ConditionTrue, &earlier, "Verified", "OriginClusterIssuer verified and ready to sign certificates"),
+				},
+			},
+			expected: v1.OriginClusterIssuerStatus{
+				Conditions: []v1.OriginClusterIssuerCondition{
+					condition(v1.ConditionAPIReachable, v1.ConditionTrue, &earlier, "Reachable", "Cloudflare API responded"),
+					condition(v1.ConditionCredentialsValid, v1.ConditionTrue, &earlier, "Verified", "Cloudflare API accepted the credentials"),
+					condition(v1.ConditionReady, v1.ConditionTrue, &earlier, "Verified", "OriginClusterIssuer verified and ready to sign certificates"),
+				},
+			},
+			error:       "Cloudflare API Error code=7003 message=Could not route to zone ray_id=0123456789abcdef-ABC",
+			provisioner: true,
+			events: []string{
+				"Warning VerificationFailed Failed to verify credentials with the Cloudflare API: Cloudflare API Error code=7003 message=Could not route to zone ray_id=0123456789abcdef-ABC",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := ctrlfake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(
+					&v1.OriginClusterIssuer{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "foo",
+						},
+						Spec: v1.OriginClusterIssuerSpec{
+							RequestType: v1.RequestTypeOriginRSA,
+							Auth: v1.OriginClusterIssuerAuthentication{
+								ServiceKeyRef: &v1.SecretKeySelector{
+									Name:      "issuer-service-key",
+									Key:       "key",
+									Namespace: "default",
+								},
+							},
+						},
+						Status: tt.status,
+					},
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "issuer-service-key",
+							Namespace: "default",
+						},
+						Data: map[string][]byte{
+							"key": []byte("djEuMC0weDAwQkFCMTBD"),
+						},
+					},
+				).
+				WithStatusSubresource(&v1.OriginClusterIssuer{}).
+				Build()
+
+			recorder := record.NewFakeRecorder(len(tt.events))
+
+			controller := &OriginClusterIssuerController{
+				Client:   client,
+				Recorder: recorder,
+				Factory: cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+					return &fakeapi.FakeClient{ListErr: tt.listErr}, nil
+				}),
+				Clock:          clock,
+				Log:            logf.Log,
+				Collection:     provisioners.CollectionWith(nil),
+				VerifyInterval: time.Hour,
+			}
+
+			result, err := reconcile.AsReconciler(client, controller).Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: "foo"},
+			})
+
+			if err != nil || tt.error != "" {
+				if diff := cmp.Diff(fmt.Sprint(err), tt.error); diff != "" {
+					t.Fatalf("diff: (-wanted +got)\n%s", diff)
+				}
+			}
+
+			if diff := cmp.Diff(result, tt.result); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+
+			got := &v1.OriginClusterIssuer{}
+			if err := client.Get(context.TODO(), types.NamespacedName{Name: "foo"}, got); err != nil {
+				t.Fatalf("expected to retrieve issuer from client: %s", err)
+			}
+			if diff := cmp.Diff(got.Status, tt.expected); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+
+			if _, ok := controller.Collection.Load(provisioners.KeyFor("", "foo")); ok != tt.provisioner {
+				t.Fatalf("expected provisioner to be stored: %t", tt.provisioner)
+			}
+
+			close(recorder.Events)
+			var events []string
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			if diff := cmp.Diff(events, tt.events); diff != "" {
+				t.Fatalf("diff: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOriginClusterIssuerVerify_Zone(t *testing.T) {
+	if err := v1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	_, api := fakeapi.NewServer(t, fake.WithZone("023e105f4ecef8ad9ca31a8372d0c353", "example.com"))
+
+	tests := []struct {
+		name   string
+		zoneID string
+		ready  v1.ConditionStatus
+	}{
+		{name: "without zone", ready: v1.ConditionTrue},
+		{name: "known zone", zoneID: "023e105f4ecef8ad9ca31a8372d0c353", ready: v1.ConditionTrue},
+		{name: "unknown zone", zoneID: "372e67954025e0ba6aaa6d586b9e0b59", ready: v1.ConditionFalse},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client := ctrlfake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(
+					&v1.OriginClusterIssuer{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "foo",
+						},
+						Spec: v1.OriginClusterIssuerSpec{
+							RequestType: v1.RequestTypeOriginRSA,
+							Auth: v1.OriginClusterIssuerAuthentication{
+								ServiceKeyRef: &v1.SecretKeySelector{
+									Name:      "issuer-service-key",
+									Key:       "key",
+									Namespace: "default",
+								},
+								ZoneID: tt.zoneID,
+							},
+						},
+					},
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "issuer-service-key",
+							Namespace: "default",
+						},
+						Data: map[string][]byte{
+							"key": []byte("djEuMC0weDAwQkFCMTBD"),
+						},
+					},
+				).
+				WithStatusSubresource(&v1.OriginClusterIssuer{}).
+				Build()
+
+			controller := &OriginClusterIssuerController{
+				Client:   client,
+				Recorder: record.NewFakeRecorder(10),
+				Factory: cfapi.FactoryFunc(func(creds cfapi.Credentials) (cfapi.Interface, error) {
+					return api, nil
+				}),
+				Clock:          fakeClock.NewFakeClock(time.Now()),
+				Log:            logf.Log,
+				Collection:     provisioners.CollectionWith(nil),
+				VerifyInterval: time.Hour,
+			}
+
+			_, _ = reconcile.AsReconciler(client, controller).Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: "foo"},
+			})
+
+			got := &v1.OriginClusterIssuer{}
+			if err := client.Get(context.TODO(), types.NamespacedName{Name: "foo"}, got); err != nil {
+				t.Fatalf("expected to retrieve issuer from client: %s", err)
+			}
+			if !IssuerHasCondition(got, v1.OriginClusterIssuerCondition{Type: v1.ConditionReady, Status: tt.ready}) {
+				t.Fatalf("expected issuer Ready to be %s, got %v", tt.ready, got.Status.Conditions)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package provisioners
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+)
+
+// The largest difference allowed between the expiration reported by the
+// Cloudflare API and the NotAfter of the returned certificate.
+const expirationTolerance = time.Minute
+
+// verifyCertificate checks that the certificate returned by the Cloudflare API
+// was issued for the CSR and hostnames requested, and returns it as normalized
+// PEM along with the parsed leaf certificate.
+func verifyCertificate(csr *x509.CertificateRequest, hostnames []string, resp *cfapi.SignResponse) ([]byte, *x509.Certificate, error) {
+	var (
+		certs []*x509.Certificate
+		rest  = []byte(resp.Certificate)
+	)
+
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return nil, nil, fmt.Errorf("unexpected PEM block of type %q", block.Type)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, nil, errors.New("no PEM encoded certificate found")
+	}
+
+	leaf := certs[0]
+
+	if !publicKeysEqual(leaf.PublicKey, csr.PublicKey) {
+		return nil, nil, errors.New("public key does not match the CSR")
+	}
+
+	for _, hostname := range hostnames {
+		if !containsFold(leaf.DNSNames, hostname) {
+			return nil, nil, fmt.Errorf("hostname %q is not one of its DNS names", hostname)
+		}
+	}
+
+	if !resp.Expiration.IsZero() {
+		if diff := leaf.NotAfter.Sub(resp.Expiration).Abs(); diff > expirationTolerance {
+			return nil, nil, fmt.Errorf("expiration %s does not match the reported expiration %s", leaf.NotAfter.UTC(), resp.Expiration.UTC())
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return buf.Bytes(), leaf, nil
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(x crypto.PublicKey) bool })
+
+	return ok && k.Equal(b)
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package provisioners
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/util/pki"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
+	"gotest.tools/v3/assert"
+)
+
+func TestVerifyCertificate(t *testing.T) {
+	newCSR := func() []byte {
+		csr, _, err := cmgen.CSR(x509.ECDSA, cmgen.SetCSRDNSNames("example.com", "*.example.com"))
+		assert.NilError(t, err)
+
+		return csr
+	}
+
+	issue := func(csr []byte, hostnames ...string) *cfapi.SignResponse {
+		return fakeapi.Issue(t, &cfapi.SignRequest{Hostnames: hostnames, Validity: 7, Type: "origin-ecc", CSR: string(csr)})
+	}
+
+	csr := newCSR()
+	resp := issue(csr, "example.com", "*.example.com")
+	other := issue(newCSR(), "example.com", "*.example.com")
+	partial := issue(csr, "example.com")
+
+	with := func(modify func(r *cfapi.SignResponse)) *cfapi.SignResponse {
+		r := *resp
+		modify(&r)
+
+		return &r
+	}
+
+	tests := []struct {
+		name  string
+		resp  *cfapi.SignResponse
+		error string
+	}{
+		{name: "valid", resp: resp},
+		{
+			name: "unnormalized pem",
+			resp: with(func(r *cfapi.SignResponse) {
+				r.Certificate = "\n" + strings.ReplaceAll(r.Certificate, "\n", "\r\n") + "\n\n"
+			}),
+		},
+		{
+			name:  "not pem",
+			resp:  with(func(r *cfapi.SignResponse) { r.Certificate = "bogus" }),
+			error: "no PEM encoded certificate found",
+		},
+		{
+			name:  "not a certificate",
+			resp:  with(func(r *cfapi.SignResponse) { r.Certificate = string(csr) }),
+			error: `unexpected PEM block of type "CERTIFICATE REQUEST"`,
+		},
+		{
+			name: "malformed certificate",
+			resp: with(func(r *cfapi.SignResponse) {
+				r.Certificate = "-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"
+			}),
+			error: "failed to parse certificate: x509: malformed certificate",
+		},
+		{
+			name:  "public key mismatch",
+			resp:  other,
+			error: "public key does not match the CSR",
+		},
+		{
+			name:  "missing hostname",
+			resp:  partial,
+			error: `hostname "*.example.com" is not one of its DNS names`,
+		},
+		{
+			name:  "expiration mismatch",
+			resp:  with(func(r *cfapi.SignResponse) { r.Expiration = r.Expiration.Add(24 * time.Hour) }),
+			error: "expiration " + resp.Expiration.UTC().String() + " does not match the reported expiration " + resp.Expiration.Add(24*time.Hour).UTC().String(),
+		},
+		{name: "no reported expiration", resp: with(func(r *cfapi.SignResponse) { r.Expiration = time.Time{} })},
+	}
+
+	req, err := pki.DecodeX509CertificateRequestBytes(csr)
+	assert.NilError(t, err)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := verifyCertificate(req, []string{"EXAMPLE.com", "*.example.com"}, tt.resp)
+			if tt.error != "" {
+				assert.Error(t, err, tt.error)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, string(got), resp.Certificate)
+		})
+	}
+}
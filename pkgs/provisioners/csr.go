@@ -0,0 +1,200 @@
+package provisioners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/cert-manager/cert-manager/pkg/util/pki"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// The minimum size of RSA keys accepted for signing.
+const minRSAKeySize = 2048
+
+var oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+
+// Origin CA certificates can only be used to authenticate servers, so any
+// usage making the certificate a CA or a client certificate is rejected.
+var unsupportedUsages = []certmanager.KeyUsage{
+	certmanager.UsageCertSign,
+	certmanager.UsageCRLSign,
+	certmanager.UsageClientAuth,
+}
+
+// Hostnames returns the hostnames a CSR requests a certificate for: its DNS
+// names or, if there are none, its CommonName.
+func Hostnames(csr *x509.CertificateRequest) []string {
+	if len(csr.DNSNames) == 0 && csr.Subject.CommonName != "" {
+		return []string{csr.Subject.CommonName}
+	}
+
+	return csr.DNSNames
+}
+
+// ValidateCSR checks that a CertificateRequest can be signed by the Origin CA
+// with the provided request type, and returns the hostnames to request along
+// with the request type to use, resolving RequestTypeAuto from the CSR's public
+// key. A *PermanentError describing the problem is returned otherwise.
+func ValidateCSR(cr *certmanager.CertificateRequest, reqType v1.RequestType) ([]string, v1.RequestType, error) {
+	csr, err := pki.DecodeX509CertificateRequestBytes(cr.Spec.Request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode CSR for signing: %w", &PermanentError{Err: err})
+	}
+
+	if reqType == v1.RequestTypeAuto {
+		switch csr.PublicKeyAlgorithm {
+		case x509.RSA:
+			reqType = v1.RequestTypeOriginRSA
+		case x509.ECDSA:
+			reqType = v1.RequestTypeOriginECC
+		}
+	}
+
+	if err := validateCSR(csr, cr, reqType); err != nil {
+		return nil, "", fmt.Errorf("invalid CSR: %w", &PermanentError{Err: err})
+	}
+
+	return Hostnames(csr), reqType, nil
+}
+
+func validateCSR(csr *x509.CertificateRequest, cr *certmanager.CertificateRequest, reqType v1.RequestType) error {
+	if err := csr.CheckSignature(); err != nil {
+		return fmt.Errorf("signature does not match the public key: %w", err)
+	}
+
+	if err := validatePublicKey(csr.PublicKey, reqType); err != nil {
+		return err
+	}
+
+	if err := validateSANs(csr); err != nil {
+		return err
+	}
+
+	return validateUsages(csr, cr)
+}
+
+func validatePublicKey(key any, reqType v1.RequestType) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if reqType != v1.RequestTypeOriginRSA {
+			return fmt.Errorf("RSA keys cannot be used with request type %s, use an ECDSA key", reqType)
+		}
+
+		if size := k.N.BitLen(); size < minRSAKeySize {
+			return fmt.Errorf("RSA key size %d is too small, must be at least %d bits", size, minRSAKeySize)
+		}
+	case *ecdsa.PublicKey:
+		if reqType != v1.RequestTypeOriginECC {
+			return fmt.Errorf("ECDSA keys cannot be used with request type %s, use an RSA key", reqType)
+		}
+
+		if k.Curve != elliptic.P256() && k.Curve != elliptic.P384() {
+			return fmt.Errorf("ECDSA curve %s is not supported, must be P-256 or P-384", k.Curve.Params().Name)
+		}
+	default:
+		return fmt.Errorf("public key type %T is not supported, must be RSA or ECDSA", key)
+	}
+
+	return nil
+}
+
+func validateSANs(csr *x509.CertificateRequest) error {
+	if len(csr.IPAddresses) > 0 {
+		return fmt.Errorf("IP address SANs are not supported: %s", strings.Join(pki.IPAddressesToString(csr.IPAddresses), ", "))
+	}
+
+	if len(csr.URIs) > 0 {
+		return fmt.Errorf("URI SANs are not supported: %s", strings.Join(pki.URLsToString(csr.URIs), ", "))
+	}
+
+	if len(csr.EmailAddresses) > 0 {
+		return fmt.Errorf("email address SANs are not supported: %s", strings.Join(csr.EmailAddresses, ", "))
+	}
+
+	cn := csr.Subject.CommonName
+
+	if len(csr.DNSNames) == 0 {
+		if cn == "" {
+			return errors.New("no DNS names or common name requested")
+		}
+
+		if !isHostname(cn) {
+			return fmt.Errorf("common name %q is not a valid hostname, set DNS names instead", cn)
+		}
+
+		return nil
+	}
+
+	if cn == "" {
+		return nil
+	}
+
+	for _, name := range csr.DNSNames {
+		if strings.EqualFold(name, cn) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("common name %q must also be requested as a DNS name", cn)
+}
+
+func validateUsages(csr *x509.CertificateRequest, cr *certmanager.CertificateRequest) error {
+	if cr.Spec.IsCA {
+		return errors.New("CA certificates are not supported")
+	}
+
+	for _, usage := range cr.Spec.Usages {
+		for _, unsupported := range unsupportedUsages {
+			if usage == unsupported {
+				return fmt.Errorf("usage %q is not supported", usage)
+			}
+		}
+	}
+
+	for _, ext := range csr.Extensions {
+		switch {
+		case ext.Id.Equal(oidExtensionBasicConstraints):
+			var constraints struct {
+				IsCA bool `asn1:"optional"`
+			}
+			if _, err := asn1.Unmarshal(ext.Value, &constraints); err != nil {
+				return fmt.Errorf("failed to parse basic constraints: %w", err)
+			}
+
+			if constraints.IsCA {
+				return errors.New("CA certificates are not supported")
+			}
+		case ext.Id.Equal(pki.OIDExtensionExtendedKeyUsage):
+			var oids []asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(ext.Value, &oids); err != nil {
+				return fmt.Errorf("failed to parse extended key usage: %w", err)
+			}
+
+			for _, oid := range oids {
+				if eku, ok := pki.ExtKeyUsageFromOID(oid); ok && eku == x509.ExtKeyUsageClientAuth {
+					return fmt.Errorf("usage %q is not supported", certmanager.UsageClientAuth)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func isHostname(name string) bool {
+	name = strings.ToLower(name)
+
+	if strings.HasPrefix(name, "*.") {
+		return len(validation.IsWildcardDNS1123Subdomain(name)) == 0
+	}
+
+	return len(validation.IsDNS1123Subdomain(name)) == 0
+}
@@ -0,0 +1,264 @@
+package provisioners
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"testing"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/cert-manager/cert-manager/pkg/util/pki"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateCSR(t *testing.T) {
+	ecKey := func(curve elliptic.Curve) crypto.Signer {
+		k, err := ecdsa.GenerateKey(curve, rand.Reader)
+		assert.NilError(t, err)
+
+		return k
+	}
+
+	rsaKey := func(bits int) crypto.Signer {
+		k, err := rsa.GenerateKey(rand.Reader, bits)
+		assert.NilError(t, err)
+
+		return k
+	}
+
+	encode := func(key crypto.Signer, template *x509.CertificateRequest) []byte {
+		der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+		assert.NilError(t, err)
+
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	}
+
+	extension := func(oid asn1.ObjectIdentifier, value any) pkix.Extension {
+		b, err := asn1.Marshal(value)
+		assert.NilError(t, err)
+
+		return pkix.Extension{Id: oid, Value: b}
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+
+	tests := []struct {
+		name      string
+		reqType   v1.RequestType
+		csr       []byte
+		usages    []certmanager.KeyUsage
+		hostnames []string
+		resolved  v1.RequestType
+		error     string
+	}{
+		{
+			name:      "ecdsa",
+			reqType:   v1.RequestTypeOriginECC,
+			csr:       encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com", "*.example.com"}}),
+			usages:    []certmanager.KeyUsage{certmanager.UsageDigitalSignature, certmanager.UsageServerAuth},
+			hostnames: []string{"example.com", "*.example.com"},
+		},
+		{
+			name:      "ecdsa p-384",
+			reqType:   v1.RequestTypeOriginECC,
+			csr:       encode(ecKey(elliptic.P384()), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			hostnames: []string{"example.com"},
+		},
+		{
+			name:      "rsa",
+			reqType:   v1.RequestTypeOriginRSA,
+			csr:       encode(rsaKey(2048), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			hostnames: []string{"example.com"},
+		},
+		{
+			name:      "auto ecdsa",
+			reqType:   v1.RequestTypeAuto,
+			csr:       encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			hostnames: []string{"example.com"},
+			resolved:  v1.RequestTypeOriginECC,
+		},
+		{
+			name:      "auto rsa",
+			reqType:   v1.RequestTypeAuto,
+			csr:       encode(rsaKey(2048), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			hostnames: []string{"example.com"},
+			resolved:  v1.RequestTypeOriginRSA,
+		},
+		{
+			name:      "common name fallback",
+			reqType:   v1.RequestTypeOriginECC,
+			csr:       encode(ecKey(elliptic.P256()), &x509.CertificateRequest{Subject: pkix.Name{CommonName: "Example.com"}}),
+			hostnames: []string{"Example.com"},
+		},
+		{
+			name:      "common name among dns names",
+			reqType:   v1.RequestTypeOriginECC,
+			csr:       encode(ecKey(elliptic.P256()), &x509.CertificateRequest{Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"example.com", "WWW.example.com"}}),
+			hostnames: []string{"example.com", "WWW.example.com"},
+		},
+		{
+			name:    "invalid pem",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     []byte("Lorem ipsum"),
+			error:   "failed to decode CSR for signing: error decoding certificate request PEM block",
+		},
+		{
+			name:    "invalid signature",
+			reqType: v1.RequestTypeOriginECC,
+			csr: (func() []byte {
+				block, _ := pem.Decode(encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}}))
+				block.Bytes[len(block.Bytes)-1] ^= 0xff
+
+				return pem.EncodeToMemory(block)
+			})(),
+			error: "invalid CSR: signature does not match the public key: x509: ECDSA verification failure",
+		},
+		{
+			name:    "small rsa key",
+			reqType: v1.RequestTypeOriginRSA,
+			csr:     encode(rsaKey(1024), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			error:   "invalid CSR: RSA key size 1024 is too small, must be at least 2048 bits",
+		},
+		{
+			name:    "unsupported curve",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P521()), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			error:   "invalid CSR: ECDSA curve P-521 is not supported, must be P-256 or P-384",
+		},
+		{
+			name:    "unsupported key type",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(edKey, &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			error:   "invalid CSR: public key type ed25519.PublicKey is not supported, must be RSA or ECDSA",
+		},
+		{
+			name:    "auto unsupported key type",
+			reqType: v1.RequestTypeAuto,
+			csr:     encode(edKey, &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			error:   "invalid CSR: public key type ed25519.PublicKey is not supported, must be RSA or ECDSA",
+		},
+		{
+			name:    "rsa key for ecc request",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(rsaKey(2048), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			error:   "invalid CSR: RSA keys cannot be used with request type OriginECC, use an ECDSA key",
+		},
+		{
+			name:    "ecdsa key for rsa request",
+			reqType: v1.RequestTypeOriginRSA,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			error:   "invalid CSR: ECDSA keys cannot be used with request type OriginRSA, use an RSA key",
+		},
+		{
+			name:    "ip address",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}, IPAddresses: []net.IP{net.ParseIP("192.0.2.1")}}),
+			error:   "invalid CSR: IP address SANs are not supported: 192.0.2.1",
+		},
+		{
+			name:    "uri",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}, URIs: []*url.URL{{Scheme: "spiffe", Host: "example.com"}}}),
+			error:   "invalid CSR: URI SANs are not supported: spiffe://example.com",
+		},
+		{
+			name:    "email",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}, EmailAddresses: []string{"admin@example.com"}}),
+			error:   "invalid CSR: email address SANs are not supported: admin@example.com",
+		},
+		{
+			name:    "no hostnames",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{}),
+			error:   "invalid CSR: no DNS names or common name requested",
+		},
+		{
+			name:    "common name not a hostname",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{Subject: pkix.Name{CommonName: "Example Inc."}}),
+			error:   `invalid CSR: common name "Example Inc." is not a valid hostname, set DNS names instead`,
+		},
+		{
+			name:    "common name not a dns name",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{Subject: pkix.Name{CommonName: "www.example.com"}, DNSNames: []string{"example.com"}}),
+			error:   `invalid CSR: common name "www.example.com" must also be requested as a DNS name`,
+		},
+		{
+			name:    "client auth usage",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			usages:  []certmanager.KeyUsage{certmanager.UsageServerAuth, certmanager.UsageClientAuth},
+			error:   `invalid CSR: usage "client auth" is not supported`,
+		},
+		{
+			name:    "cert sign usage",
+			reqType: v1.RequestTypeOriginECC,
+			csr:     encode(ecKey(elliptic.P256()), &x509.CertificateRequest{DNSNames: []string{"example.com"}}),
+			usages:  []certmanager.KeyUsage{certmanager.UsageCertSign},
+			error:   `invalid CSR: usage "cert sign" is not supported`,
+		},
+		{
+			name:    "client auth extension",
+			reqType: v1.RequestTypeOriginECC,
+			csr: encode(ecKey(elliptic.P256()), &x509.CertificateRequest{
+				DNSNames: []string{"example.com"},
+				ExtraExtensions: []pkix.Extension{
+					extension(pki.OIDExtensionExtendedKeyUsage, []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 1}, {1, 3, 6, 1, 5, 5, 7, 3, 2}}),
+				},
+			}),
+			error: `invalid CSR: usage "client auth" is not supported`,
+		},
+		{
+			name:    "ca extension",
+			reqType: v1.RequestTypeOriginECC,
+			csr: encode(ecKey(elliptic.P256()), &x509.CertificateRequest{
+				DNSNames: []string{"example.com"},
+				ExtraExtensions: []pkix.Extension{
+					extension(oidExtensionBasicConstraints, struct{ IsCA bool }{IsCA: true}),
+				},
+			}),
+			error: "invalid CSR: CA certificates are not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cr := cmgen.CertificateRequest("foobar",
+				cmgen.SetCertificateRequestCSR(tt.csr),
+				cmgen.SetCertificateRequestKeyUsages(tt.usages...),
+			)
+
+			hostnames, reqType, err := ValidateCSR(cr, tt.reqType)
+			if tt.error != "" {
+				assert.Error(t, err, tt.error)
+				assert.Assert(t, IsPermanent(err), "expected permanent error, got %v", err)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, hostnames, tt.hostnames)
+
+			if tt.resolved != "" {
+				assert.Equal(t, reqType, tt.resolved)
+			} else {
+				assert.Equal(t, reqType, tt.reqType)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package provisioners
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+)
+
+// The number of signed certificates each provisioner remembers.
+const issuedCacheSize = 256
+
+// Getter retrieves certificates previously signed by the Origin CA. Signers
+// implementing it allow certificates recorded on a CertificateRequest to be
+// reused instead of signing a new one.
+type Getter interface {
+	Get(ctx context.Context, id string) (*cfapi.Certificate, error)
+}
+
+// IssuanceKey identifies a signing attempt: a CertificateRequest is only
+// signed once for a given UID and CSR.
+func IssuanceKey(cr *certmanager.CertificateRequest) string {
+	sum := sha256.Sum256(cr.Spec.Request)
+
+	return string(cr.UID) + "/" + hex.EncodeToString(sum[:])
+}
+
+// issuedCache remembers recently signed certificates by issuance key, evicting
+// the oldest once full. It belongs to a single provisioner, and issuers replace
+// their provisioner whenever their credentials are verified again or their
+// Secret changes, which clears it. Certificates are then only reused from the
+// annotations recorded on the CertificateRequest.
+type issuedCache struct {
+	mu    sync.Mutex
+	m     map[string]*SignResponse
+	order []string
+}
+
+func (c *issuedCache) load(key string) (*SignResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, ok := c.m[key]
+
+	return resp, ok
+}
+
+func (c *issuedCache) store(key string, resp *SignResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.m == nil {
+		c.m = make(map[string]*SignResponse)
+	}
+
+	if _, ok := c.m[key]; !ok {
+		c.order = append(c.order, key)
+	}
+
+	c.m[key] = resp
+
+	for len(c.order) > issuedCacheSize {
+		delete(c.m, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// reuse returns the certificate already signed for a CertificateRequest, either
+// remembered by the provisioner or recorded in the CertificateRequest's
+// annotations. It returns nil if no certificate can be reused, and a new one
+// must be signed.
+func (p *Provisioner) reuse(ctx context.Context, cr *certmanager.CertificateRequest, csr *x509.CertificateRequest, hostnames []string, reqType v1.RequestType, validity int) (*SignResponse, error) {
+	key := IssuanceKey(cr)
+
+	if resp, ok := p.issued.load(key); ok {
+		reused := *resp
+		reused.Reused = true
+
+		return &reused, nil
+	}
+
+	id := cr.Annotations[v1.CertificateIDAnnotation]
+	if id == "" || cr.Annotations[v1.IssuanceKeyAnnotation] != key {
+		return nil, nil
+	}
+
+	getter, ok := p.client.(Getter)
+	if !ok {
+		return nil, nil
+	}
+
+	cert, err := getter.Get(ctx, id)
+	if cfapi.IsValidation(err) {
+		// The certificate no longer exists, for example because it was revoked.
+		p.log.Info("certificate recorded on CertificateRequest not found, signing a new one", "id", id)
+
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve certificate %s: %w", id, err)
+	}
+
+	certPem, leaf, err := verifyCertificate(csr, hostnames, cert)
+	if err != nil {
+		p.log.Info("certificate recorded on CertificateRequest does not match, signing a new one", "id", id, "reason", err.Error())
+
+		return nil, nil
+	}
+
+	resp := &SignResponse{
+		Certificate: certPem,
+		ID:          id,
+		RequestType: reqType,
+		Validity:    validity,
+		Expiration:  leaf.NotAfter,
+		RayID:       cr.Annotations[v1.RayIDAnnotation],
+		Reused:      true,
+	}
+	p.issued.store(key, resp)
+
+	return resp, nil
+}
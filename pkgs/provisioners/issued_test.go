@@ -0,0 +1,105 @@
+package provisioners
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+)
+
+func TestSign_Idempotent(t *testing.T) {
+	ctx := context.Background()
+
+	newCSR := func() []byte {
+		csr, _, err := cmgen.CSR(x509.ECDSA, cmgen.SetCSRDNSNames("example.com"))
+		assert.NilError(t, err)
+
+		return csr
+	}
+
+	csr := newCSR()
+	cr := cmgen.CertificateRequest("foobar", cmgen.SetCertificateRequestCSR(csr))
+	cr.UID = "4a3e6dca-3f2b-4d6b-a4e3-0d5a5b1b1c1d"
+
+	server, client := fakeapi.NewServer(t)
+
+	signed := func() int {
+		n, _ := server.Counts()
+
+		return n
+	}
+
+	p, err := New(client, v1.RequestTypeOriginECC, logr.Discard())
+	assert.NilError(t, err)
+
+	first, err := p.Sign(ctx, cr)
+	assert.NilError(t, err)
+	assert.Assert(t, !first.Reused)
+
+	second, err := p.Sign(ctx, cr)
+	assert.NilError(t, err)
+	assert.Assert(t, second.Reused, "expected remembered certificate")
+	assert.DeepEqual(t, second.Certificate, first.Certificate)
+	assert.Equal(t, second.ID, first.ID)
+	assert.Assert(t, !first.Reused, "remembered certificate was modified")
+	assert.Equal(t, signed(), 1)
+
+	// A new provisioner, such as after a restart, reuses the certificate
+	// recorded on the CertificateRequest.
+	annotated := cr.DeepCopy()
+	annotated.Annotations = map[string]string{
+		v1.CertificateIDAnnotation: first.ID,
+		v1.IssuanceKeyAnnotation:   IssuanceKey(cr),
+	}
+
+	p, err = New(client, v1.RequestTypeOriginECC, logr.Discard())
+	assert.NilError(t, err)
+
+	reused, err := p.Sign(ctx, annotated)
+	assert.NilError(t, err)
+	assert.Assert(t, reused.Reused)
+	assert.DeepEqual(t, reused.Certificate, first.Certificate)
+	assert.Equal(t, reused.ID, first.ID)
+	assert.Equal(t, signed(), 1)
+
+	// A different CSR on the same CertificateRequest must be signed again.
+	changed := annotated.DeepCopy()
+	changed.Spec.Request = newCSR()
+
+	resp, err := p.Sign(ctx, changed)
+	assert.NilError(t, err)
+	assert.Assert(t, !resp.Reused)
+	assert.Equal(t, signed(), 2)
+
+	// So must a certificate that no longer exists.
+	_, err = client.Revoke(ctx, first.ID)
+	assert.NilError(t, err)
+
+	p, err = New(client, v1.RequestTypeOriginECC, logr.Discard())
+	assert.NilError(t, err)
+
+	resp, err = p.Sign(ctx, annotated)
+	assert.NilError(t, err)
+	assert.Assert(t, !resp.Reused)
+	assert.Equal(t, signed(), 3)
+}
+
+func TestIssuedCache(t *testing.T) {
+	c := issuedCache{}
+
+	for i := 0; i <= issuedCacheSize; i++ {
+		c.store(string(rune('a'+i)), &SignResponse{Validity: i})
+	}
+
+	_, ok := c.load("a")
+	assert.Assert(t, !ok, "expected oldest entry to be evicted")
+
+	resp, ok := c.load(string(rune('a' + issuedCacheSize)))
+	assert.Assert(t, ok)
+	assert.Equal(t, resp.Validity, issuedCacheSize)
+}
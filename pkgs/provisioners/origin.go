@@ -1,20 +1,21 @@
 // Package provisioners provides a mapping between CertificateRequest
 // and the Cloudflare API, with credentials already bounded by an
-// OriginClusterIssuer.
+// OriginIssuer or OriginClusterIssuer.
 package provisioners
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
+	"time"
 
 	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	"github.com/cert-manager/cert-manager/pkg/util/pki"
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/go-logr/logr"
-	"k8s.io/apimachinery/pkg/types"
 )
 
 const (
@@ -24,17 +25,55 @@ const (
 
 var allowedValidty = []int{7, 30, 90, 365, 730, 1095, 5475}
 
-// Collection stores cached Provisioners, stored by namespaced names of the
-// issuer.
+// Collection stores cached Provisioners, stored by the kind and namespaced
+// name of the issuer.
 type Collection struct {
 	m sync.Map
+
+	// loadMu serializes calls to loader, so that a provisioner is only built
+	// once.
+	loadMu sync.Mutex
+	loader Loader
+}
+
+// Loader builds the provisioner of an issuer missing from a Collection.
+type Loader func(ctx context.Context, key Key) (*Provisioner, error)
+
+// deleted marks a provisioner that was deleted from a Collection, and must not
+// be built again by its loader.
+type deleted struct{}
+
+// Key identifies the issuer a provisioner was created for. OriginClusterIssuers
+// are cluster scoped, so their keys have an empty namespace.
+type Key struct {
+	Kind      string
+	Namespace string
+	Name      string
 }
 
-// A CollectionItem allows for the namespaced name and provisioner to
+// KeyFor returns the key of a namespaced OriginIssuer, or of an
+// OriginClusterIssuer if namespace is empty.
+func KeyFor(namespace, name string) Key {
+	if namespace == "" {
+		return Key{Kind: v1.OriginClusterIssuerKind, Name: name}
+	}
+
+	return Key{Kind: v1.OriginIssuerKind, Namespace: namespace, Name: name}
+}
+
+func (k Key) String() string {
+	if k.Namespace == "" {
+		return k.Kind + "/" + k.Name
+	}
+
+	return k.Kind + "/" + k.Namespace + "/" + k.Name
+}
+
+// A CollectionItem allows for the issuer key and provisioner to
 // be stored together.
 type CollectionItem struct {
-	NamespacedName types.NamespacedName
-	Provisioner    *Provisioner
+	Key         Key
+	Provisioner *Provisioner
 }
 
 // CollectionWith returns a Collection storing the provided provisioners.
@@ -42,7 +81,7 @@ func CollectionWith(items []CollectionItem) *Collection {
 	c := &Collection{}
 
 	for _, i := range items {
-		c.Store(i.NamespacedName, i.Provisioner)
+		c.Store(i.Key, i.Provisioner)
 	}
 
 	return c
@@ -54,7 +93,23 @@ type Provisioner struct {
 	client Signer
 	log    logr.Logger
 
-	reqType v1.RequestType
+	reqType  v1.RequestType
+	validity v1.OriginIssuerValidity
+
+	issued issuedCache
+}
+
+// Option configures optional behaviour of a Provisioner.
+type Option func(p *Provisioner)
+
+// WithValidity maps the durations requested by CertificateRequests to validity
+// periods according to the provided policy.
+func WithValidity(validity *v1.OriginIssuerValidity) Option {
+	return func(p *Provisioner) {
+		if validity != nil {
+			p.validity = *validity
+		}
+	}
 }
 
 // Signer implements the Origin CA signing API.
@@ -63,25 +118,85 @@ type Signer interface {
 }
 
 // New returns a new provisioner.
-func New(client Signer, reqType v1.RequestType, log logr.Logger) (*Provisioner, error) {
+func New(client Signer, reqType v1.RequestType, log logr.Logger, options ...Option) (*Provisioner, error) {
 	p := &Provisioner{
 		client:  client,
 		log:     log,
 		reqType: reqType,
 	}
 
+	for _, o := range options {
+		o(p)
+	}
+
 	return p, nil
 }
 
 // Store adds a provisioner to the collection.
-func (c *Collection) Store(namespacedName types.NamespacedName, provisioner *Provisioner) {
-	c.m.Store(namespacedName, provisioner)
+func (c *Collection) Store(key Key, provisioner *Provisioner) {
+	c.m.Store(key, provisioner)
+}
+
+// Delete removes the provisioner stored with the provided key, if any. It is
+// not built again by the collection's loader until a provisioner is stored, or
+// the key is forgotten.
+func (c *Collection) Delete(key Key) {
+	c.m.Store(key, deleted{})
+}
+
+// Forget removes everything stored with the provided key, including the record
+// of a deleted provisioner. It is called once the issuer is gone from the
+// cache, so that the collection does not grow with every issuer ever deleted.
+func (c *Collection) Forget(key Key) {
+	c.m.Delete(key)
+}
+
+// SetLoader sets the function building provisioners missing from the
+// collection in LoadOrBuild.
+func (c *Collection) SetLoader(loader Loader) {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	c.loader = loader
+}
+
+// LoadOrBuild returns the stored provisioner. A provisioner that was never
+// stored is built with the collection's loader, if any, and stored. It returns
+// false if no provisioner could be loaded or built.
+func (c *Collection) LoadOrBuild(ctx context.Context, key Key) (*Provisioner, bool, error) {
+	if p, ok := c.Load(key); ok {
+		return p, true, nil
+	}
+
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+
+	if c.loader == nil {
+		return nil, false, nil
+	}
+
+	// The provisioner may have been stored, or deleted, while waiting.
+	if v, ok := c.m.Load(key); ok {
+		p, ok := v.(*Provisioner)
+
+		return p, ok, nil
+	}
+
+	p, err := c.loader(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	v, _ := c.m.LoadOrStore(key, p)
+	p, ok := v.(*Provisioner)
+
+	return p, ok, nil
 }
 
 // Load returns the stored provisioner, or returns false if nothing is cached with
-// the proved namespaced name.
-func (c *Collection) Load(namespacedName types.NamespacedName) (*Provisioner, bool) {
-	v, ok := c.m.Load(namespacedName)
+// the provided key.
+func (c *Collection) Load(key Key) (*Provisioner, bool) {
+	v, ok := c.m.Load(key)
 	if !ok {
 		return nil, ok
 	}
@@ -91,35 +206,90 @@ func (c *Collection) Load(namespacedName types.NamespacedName) (*Provisioner, bo
 	return p, ok
 }
 
-// Sign uses the Cloduflare API to sign a CertificateRequest. The validity of the CertificateRequest is
-// normalized to the closests validity allowed by the Cloudflare API, which make be significantly different
-// than the validity provided.
-func (p *Provisioner) Sign(ctx context.Context, cr *certmanager.CertificateRequest) (certPem []byte, err error) {
-	csr, err := pki.DecodeX509CertificateRequestBytes(cr.Spec.Request)
+// RequestType returns the request type a CertificateRequest is signed with:
+// the one set by its RequestTypeAnnotation, if any, or the provisioner's. A
+// *PermanentError is returned if the annotation is not a valid request type.
+func (p *Provisioner) RequestType(cr *certmanager.CertificateRequest) (v1.RequestType, error) {
+	override, ok := cr.Annotations[v1.RequestTypeAnnotation]
+	if !ok {
+		return p.reqType, nil
+	}
+
+	switch reqType := v1.RequestType(override); reqType {
+	case v1.RequestTypeOriginRSA, v1.RequestTypeOriginECC, v1.RequestTypeAuto:
+		return reqType, nil
+	default:
+		return "", &PermanentError{Err: fmt.Errorf("annotation %s has invalid value %q, must be one of %s, %s or %s",
+			v1.RequestTypeAnnotation, override, v1.RequestTypeOriginRSA, v1.RequestTypeOriginECC, v1.RequestTypeAuto)}
+	}
+}
+
+// SignResponse is a certificate signed by the Cloudflare API, along with the
+// metadata needed to trace it back to the Cloudflare dashboard.
+type SignResponse struct {
+	// Certificate is the PEM encoded certificate, verified against the CSR.
+	Certificate []byte
+	// ID is the Cloudflare ID of the certificate.
+	ID string
+	// RequestType is the request type the certificate was signed with.
+	RequestType v1.RequestType
+	// Validity is the validity period, in days, the certificate was signed with.
+	Validity int
+	// Expiration is the time the certificate expires.
+	Expiration time.Time
+	// RayID is the CF-Ray ID of the signing request.
+	RayID string
+	// Reused is true if the certificate had already been signed for the
+	// CertificateRequest, and was reused instead of signing a new one.
+	Reused bool
+}
+
+// Sign uses the Cloduflare API to sign a CertificateRequest. The CSR is validated
+// first, so that requests the Origin CA cannot fulfill fail with a *PermanentError
+// explaining why. The returned certificate is checked against the CSR and
+// re-encoded, so a mismatching certificate is never handed to cert-manager.
+// The validity of the CertificateRequest is normalized to a validity allowed by the Cloudflare API according to the provisioner's validity policy,
+// which by default is the closest one and may be significantly different than the validity provided.
+//
+// Signing is idempotent: a certificate already signed for the same CertificateRequest UID and CSR,
+// remembered by the provisioner or recorded in the CertificateRequest's annotations, is returned
+// instead of signing a new one.
+func (p *Provisioner) Sign(ctx context.Context, cr *certmanager.CertificateRequest) (*SignResponse, error) {
+	reqType, err := p.RequestType(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames, reqType, err := ValidateCSR(cr, reqType)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := p.Validity(cr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode CSR for signing: %s", err)
+		return nil, err
 	}
 
-	hostnames := csr.DNSNames
-	var duration int
-	if cr.Spec.Duration == nil {
-		duration = DefaultDurationInternval
-	} else {
-		duration = closest(int(cr.Spec.Duration.Duration.Hours()/24), allowedValidty)
+	// The CSR has already been decoded successfully by ValidateCSR.
+	csr, _ := pki.DecodeX509CertificateRequestBytes(cr.Spec.Request)
+
+	reused, err := p.reuse(ctx, cr, csr, hostnames, reqType, duration)
+	if err != nil || reused != nil {
+		return reused, err
 	}
 
-	var reqType string
-	switch p.reqType {
+	var typ string
+	switch reqType {
 	case v1.RequestTypeOriginECC:
-		reqType = "origin-ecc"
+		typ = "origin-ecc"
 	case v1.RequestTypeOriginRSA:
-		reqType = "origin-rsa"
+		typ = "origin-rsa"
 	}
 
 	resp, err := p.client.Sign(ctx, &cfapi.SignRequest{
 		Hostnames: hostnames,
 		Validity:  duration,
-		Type:      reqType,
+		Type:      typ,
 		CSR:       string(cr.Spec.Request),
 	})
 
@@ -127,7 +297,49 @@ func (p *Provisioner) Sign(ctx context.Context, cr *certmanager.CertificateReque
 		return nil, fmt.Errorf("unable to sign request: %w", err)
 	}
 
-	return []byte(resp.Certificate), nil
+	certPem, leaf, err := verifyCertificate(csr, hostnames, resp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate returned by the Cloudflare API: %w", &PermanentError{Err: err})
+	}
+
+	signed := &SignResponse{
+		Certificate: certPem,
+		ID:          resp.Id,
+		RequestType: reqType,
+		Validity:    duration,
+		Expiration:  leaf.NotAfter,
+		RayID:       resp.RayID,
+	}
+	p.issued.store(IssuanceKey(cr), signed)
+
+	return signed, nil
+}
+
+// PermanentError wraps errors for CertificateRequests that can never be signed,
+// no matter how often the request is retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent reports whether an error returned by Sign will recur if the
+// CertificateRequest is retried: the request itself is invalid, it was rejected
+// by the Cloudflare API, or the issuer's credentials were refused. Any other
+// error, such as a network failure or rate limiting, is considered transient.
+func IsPermanent(err error) bool {
+	var perr *PermanentError
+	if errors.As(err, &perr) {
+		return true
+	}
+
+	return cfapi.IsValidation(err) || cfapi.IsAuth(err)
 }
 
 func closest(of int, valid []int) int {
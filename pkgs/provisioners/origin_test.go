@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"testing"
 	"testing/quick"
 	"time"
@@ -11,6 +12,7 @@ import (
 	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
 	"github.com/cloudflare/origin-ca-issuer/internal/cfapi"
+	fakeapi "github.com/cloudflare/origin-ca-issuer/internal/cfapi/testing"
 	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -20,22 +22,30 @@ import (
 
 func TestSign(t *testing.T) {
 	type testCase struct {
-		name     string
-		reqType  v1.RequestType
-		req      *certmanager.CertificateRequest
-		signReq  *cfapi.SignRequest
-		expected []byte
+		name       string
+		reqType    v1.RequestType
+		signedType v1.RequestType
+		req        *certmanager.CertificateRequest
+		signReq    *cfapi.SignRequest
 	}
 
 	run := func(t *testing.T, tc testCase) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		_, client := fakeapi.NewServer(t)
+
+		var resp *cfapi.SignResponse
 		signer := SignerFunc(func(ctx context.Context, req *cfapi.SignRequest) (*cfapi.SignResponse, error) {
 			assert.DeepEqual(t, req, tc.signReq, cmpopts.IgnoreFields(cfapi.SignRequest{}, "CSR"))
-			return &cfapi.SignResponse{
-				Certificate: "-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n",
-			}, nil
+
+			var err error
+			resp, err = client.Sign(ctx, req)
+			if err == nil {
+				resp.RayID = "0123456789abcdef-ABC"
+			}
+
+			return resp, err
 		})
 
 		provisioner, err := New(signer, tc.reqType, logr.Discard())
@@ -43,7 +53,20 @@ func TestSign(t *testing.T) {
 
 		res, err := provisioner.Sign(ctx, tc.req)
 		assert.NilError(t, err)
-		assert.DeepEqual(t, res, tc.expected)
+
+		signedType := tc.reqType
+		if tc.signedType != "" {
+			signedType = tc.signedType
+		}
+
+		assert.DeepEqual(t, res, &SignResponse{
+			Certificate: []byte(resp.Certificate),
+			ID:          resp.Id,
+			RequestType: signedType,
+			Validity:    tc.signReq.Validity,
+			Expiration:  resp.Expiration,
+			RayID:       "0123456789abcdef-ABC",
+		})
 	}
 
 	testCases := []testCase{
@@ -66,7 +89,6 @@ func TestSign(t *testing.T) {
 				Type:      "origin-rsa",
 				CSR:       "",
 			},
-			expected: []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"),
 		},
 		{
 			name:    "origin ecc",
@@ -87,7 +109,47 @@ func TestSign(t *testing.T) {
 				Type:      "origin-ecc",
 				CSR:       "",
 			},
-			expected: []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"),
+		},
+		{
+			name:       "auto",
+			reqType:    v1.RequestTypeAuto,
+			signedType: v1.RequestTypeOriginRSA,
+			req: cmgen.CertificateRequest("foobar",
+				cmgen.SetCertificateRequestNamespace("default"),
+				cmgen.SetCertificateRequestCSR((func() []byte {
+					csr, _, err := cmgen.CSR(x509.RSA, cmgen.SetCSRDNSNames("example.com"))
+					assert.NilError(t, err)
+
+					return csr
+				})()),
+			),
+			signReq: &cfapi.SignRequest{
+				Hostnames: []string{"example.com"},
+				Validity:  7,
+				Type:      "origin-rsa",
+				CSR:       "",
+			},
+		},
+		{
+			name:       "request type annotation",
+			reqType:    v1.RequestTypeOriginECC,
+			signedType: v1.RequestTypeOriginRSA,
+			req: cmgen.CertificateRequest("foobar",
+				cmgen.SetCertificateRequestNamespace("default"),
+				cmgen.SetCertificateRequestAnnotations(map[string]string{v1.RequestTypeAnnotation: "OriginRSA"}),
+				cmgen.SetCertificateRequestCSR((func() []byte {
+					csr, _, err := cmgen.CSR(x509.RSA, cmgen.SetCSRDNSNames("example.com"))
+					assert.NilError(t, err)
+
+					return csr
+				})()),
+			),
+			signReq: &cfapi.SignRequest{
+				Hostnames: []string{"example.com"},
+				Validity:  7,
+				Type:      "origin-rsa",
+				CSR:       "",
+			},
 		},
 		{
 			name:    "find closest duration",
@@ -108,7 +170,6 @@ func TestSign(t *testing.T) {
 				Type:      "origin-ecc",
 				CSR:       "",
 			},
-			expected: []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"),
 		},
 		{
 			name:    "default duration",
@@ -128,7 +189,6 @@ func TestSign(t *testing.T) {
 				Type:      "origin-ecc",
 				CSR:       "",
 			},
-			expected: []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"),
 		},
 	}
 
@@ -165,6 +225,65 @@ func TestSign_Error(t *testing.T) {
 	assert.Error(t, err, "unable to sign request: cfapi error")
 }
 
+func TestRequestType(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    v1.RequestType
+		error       string
+	}{
+		{name: "issuer", expected: v1.RequestTypeOriginECC},
+		{name: "override", annotations: map[string]string{v1.RequestTypeAnnotation: "Auto"}, expected: v1.RequestTypeAuto},
+		{
+			name:        "invalid override",
+			annotations: map[string]string{v1.RequestTypeAnnotation: "origin-rsa"},
+			error:       `annotation cert-manager.k8s.cloudflare.com/request-type has invalid value "origin-rsa", must be one of OriginRSA, OriginECC or Auto`,
+		},
+	}
+
+	provisioner, err := New(nil, v1.RequestTypeOriginECC, logr.Discard())
+	assert.NilError(t, err)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provisioner.RequestType(cmgen.CertificateRequest("foobar", cmgen.SetCertificateRequestAnnotations(tt.annotations)))
+			if tt.error != "" {
+				assert.Error(t, err, tt.error)
+				assert.Assert(t, IsPermanent(err))
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.expected)
+		})
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{name: "invalid request", err: fmt.Errorf("failed to decode CSR for signing: %w", &PermanentError{Err: errors.New("bad pem")}), permanent: true},
+		{name: "validation", err: fmt.Errorf("unable to sign request: %w", &cfapi.Error{Class: cfapi.ErrorClassValidation}), permanent: true},
+		{name: "auth", err: fmt.Errorf("unable to sign request: %w", &cfapi.Error{Class: cfapi.ErrorClassAuth}), permanent: true},
+		{name: "rate limited", err: fmt.Errorf("unable to sign request: %w", &cfapi.Error{Class: cfapi.ErrorClassRateLimited}), permanent: false},
+		{name: "server", err: fmt.Errorf("unable to sign request: %w", &cfapi.Error{Class: cfapi.ErrorClassServer}), permanent: false},
+		{name: "network", err: fmt.Errorf("unable to sign request: %w", &cfapi.Error{Class: cfapi.ErrorClassNetwork}), permanent: false},
+		{name: "unknown", err: errors.New("cfapi error"), permanent: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, IsPermanent(tt.err), tt.permanent)
+		})
+	}
+}
+
 func TestClosest(t *testing.T) {
 	index := func(x int, s []int) int {
 		for i, n := range s {
@@ -185,6 +304,63 @@ func TestClosest(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestCollection_LoadOrBuild(t *testing.T) {
+	p, err := New(&fakeapi.FakeClient{}, v1.RequestTypeOriginECC, logr.Discard())
+	assert.NilError(t, err)
+
+	ctx := context.Background()
+	key := KeyFor("default", "foo")
+
+	c := &Collection{}
+
+	_, ok, err := c.LoadOrBuild(ctx, key)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok, "loaded provisioner without a loader")
+
+	calls := 0
+	c.SetLoader(func(ctx context.Context, k Key) (*Provisioner, error) {
+		calls++
+		if k.Name == "broken" {
+			return nil, errors.New("bad credentials")
+		}
+
+		return p, nil
+	})
+
+	got, ok, err := c.LoadOrBuild(ctx, key)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, got, p)
+
+	got, ok, err = c.LoadOrBuild(ctx, key)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, got, p)
+	assert.Equal(t, calls, 1)
+
+	_, _, err = c.LoadOrBuild(ctx, KeyFor("default", "broken"))
+	assert.Error(t, err, "bad credentials")
+
+	c.Delete(key)
+
+	_, ok, err = c.LoadOrBuild(ctx, key)
+	assert.NilError(t, err)
+	assert.Assert(t, !ok, "built deleted provisioner")
+
+	c.Forget(key)
+
+	_, ok, err = c.LoadOrBuild(ctx, key)
+	assert.NilError(t, err)
+	assert.Assert(t, ok, "forgotten provisioner was not built")
+	assert.Equal(t, calls, 3)
+
+	c.Delete(key)
+	c.Store(key, p)
+
+	_, ok = c.Load(key)
+	assert.Assert(t, ok)
+}
+
 type SignerFunc func(ctx context.Context, req *cfapi.SignRequest) (*cfapi.SignResponse, error)
 
 func (f SignerFunc) Sign(ctx context.Context, req *cfapi.SignRequest) (*cfapi.SignResponse, error) {
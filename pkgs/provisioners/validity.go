@@ -0,0 +1,90 @@
+package provisioners
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+)
+
+const day = 24 * time.Hour
+
+// Validity returns the validity period, in days, a CertificateRequest will be
+// signed with under the provisioner's validity policy. A *PermanentError is
+// returned if the requested duration is not allowed.
+func (p *Provisioner) Validity(cr *certmanager.CertificateRequest) (int, error) {
+	policy := p.validity
+
+	if cr.Spec.Duration == nil {
+		if policy.DefaultDays != 0 {
+			return policy.DefaultDays, nil
+		}
+
+		return DefaultDurationInternval, nil
+	}
+
+	requested := cr.Spec.Duration.Duration
+
+	if policy.MaxDays != 0 && requested > time.Duration(policy.MaxDays)*day {
+		if policy.Rounding == v1.ValidityRoundingStrict {
+			return 0, &PermanentError{Err: fmt.Errorf("requested duration %s exceeds the maximum validity of %d days", requested, policy.MaxDays)}
+		}
+
+		return policy.MaxDays, nil
+	}
+
+	days := int(requested / day)
+
+	switch policy.Rounding {
+	case v1.ValidityRoundingUp:
+		if requested%day != 0 {
+			days++
+		}
+
+		return roundUp(days, allowedValidty), nil
+	case v1.ValidityRoundingDown:
+		return roundDown(days, allowedValidty), nil
+	case v1.ValidityRoundingStrict:
+		if requested%day != 0 || !IsAllowedValidity(days) {
+			return 0, &PermanentError{Err: fmt.Errorf("requested duration %s is not a validity period supported by the Cloudflare API, must be one of %v days", requested, allowedValidty)}
+		}
+
+		return days, nil
+	}
+
+	return closest(days, allowedValidty), nil
+}
+
+// IsAllowedValidity reports whether days is a validity period supported by the
+// Cloudflare API.
+func IsAllowedValidity(days int) bool {
+	return roundDown(days, allowedValidty) == days
+}
+
+// roundUp returns the smallest valid value that is at least of, or the largest
+// valid value if of exceeds all of them. valid must be sorted.
+func roundUp(of int, valid []int) int {
+	i := sort.SearchInts(valid, of)
+	if i == len(valid) {
+		return valid[len(valid)-1]
+	}
+
+	return valid[i]
+}
+
+// roundDown returns the largest valid value that is at most of, or the smallest
+// valid value if of is below all of them. valid must be sorted.
+func roundDown(of int, valid []int) int {
+	i := sort.SearchInts(valid, of)
+	if i < len(valid) && valid[i] == of {
+		return of
+	}
+
+	if i == 0 {
+		return valid[0]
+	}
+
+	return valid[i-1]
+}
@@ -0,0 +1,73 @@
+package provisioners
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	v1 "github.com/cloudflare/origin-ca-issuer/pkgs/apis/v1"
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidity(t *testing.T) {
+	days := func(d float64) *metav1.Duration {
+		return &metav1.Duration{Duration: time.Duration(d * float64(24*time.Hour))}
+	}
+
+	tests := []struct {
+		name     string
+		policy   *v1.OriginIssuerValidity
+		duration *metav1.Duration
+		expected int
+		error    string
+	}{
+		{name: "default", expected: 7},
+		{name: "policy default", policy: &v1.OriginIssuerValidity{DefaultDays: 90}, expected: 90},
+		{name: "nearest", duration: days(10), expected: 7},
+		{name: "explicit nearest", policy: &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingNearest}, duration: days(80), expected: 90},
+		{name: "up", policy: &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingUp}, duration: days(10), expected: 30},
+		{name: "up partial day", policy: &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingUp}, duration: days(7.5), expected: 30},
+		{name: "up beyond largest", policy: &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingUp}, duration: days(6000), expected: 5475},
+		{name: "down", policy: &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingDown}, duration: days(80), expected: 30},
+		{name: "down below smallest", policy: &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingDown}, duration: days(1), expected: 7},
+		{name: "strict", policy: &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingStrict}, duration: days(365), expected: 365},
+		{
+			name:     "strict mismatch",
+			policy:   &v1.OriginIssuerValidity{Rounding: v1.ValidityRoundingStrict},
+			duration: days(10),
+			error:    "requested duration 240h0m0s is not a validity period supported by the Cloudflare API, must be one of [7 30 90 365 730 1095 5475] days",
+		},
+		{name: "max", policy: &v1.OriginIssuerValidity{MaxDays: 90}, duration: days(365), expected: 90},
+		{name: "max with rounding", policy: &v1.OriginIssuerValidity{MaxDays: 90, Rounding: v1.ValidityRoundingUp}, duration: days(91), expected: 90},
+		{
+			name:     "strict max",
+			policy:   &v1.OriginIssuerValidity{MaxDays: 90, Rounding: v1.ValidityRoundingStrict},
+			duration: days(365),
+			error:    "requested duration 8760h0m0s exceeds the maximum validity of 90 days",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(nil, v1.RequestTypeOriginECC, logr.Discard(), WithValidity(tt.policy))
+			assert.NilError(t, err)
+
+			cr := cmgen.CertificateRequest("foobar", cmgen.SetCertificateRequestDuration(tt.duration))
+
+			got, err := p.Validity(cr)
+			if tt.error != "" {
+				assert.Error(t, err, tt.error)
+				assert.Assert(t, IsPermanent(err), "expected permanent error, got %v", err)
+
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, got, tt.expected, fmt.Sprintf("requested %v", tt.duration))
+		})
+	}
+}